@@ -5,6 +5,7 @@ import (
 	"image/color"
 
 	"github.com/hajimehoshi/ebiten/v2"
+	"golang.org/x/image/font"
 
 	"github.com/dimchansky/dcs-hmd/gui/indicator"
 )
@@ -18,6 +19,7 @@ type IndicatorConfig struct {
 	Color           color.NRGBA
 	BorderColor     color.NRGBA
 	Rect            image.Rectangle
+	FontFace        font.Face
 }
 
 const (
@@ -28,36 +30,39 @@ const (
 
 func NewIndicator(cfg *IndicatorConfig) *Indicator {
 	return &Indicator{
-		impl: indicator.New(
-			&indicator.Config{
-				Width:               cfg.Width,
-				Height:              cfg.Height,
-				TickLength:          cfg.TickLength,
-				MinorTickLength:     cfg.MinorTickLength,
-				LineWidth:           cfg.LineWidth,
-				Color:               cfg.Color,
-				BorderColor:         cfg.BorderColor,
-				Rect:                cfg.Rect,
-				MinValue:            minPitch,
-				MaxValue:            maxPitch,
-				MinFixedWindowValue: minPitch,
-				MaxFixedWindowValue: maxPitch,
-				MinTickStep:         pitchStep,
-				GetTickLength: func(rotorPitchValue int) float64 {
-					tickLen := float64(cfg.MinorTickLength)
-					if rotorPitchValue%2 != 0 {
-						tickLen = float64(cfg.TickLength)
-					}
-					return tickLen
-				},
-				MinSafeValue:    nil,
-				MaxAllowedValue: nil,
-				MinLabelStep:    2,
-				GetLabelOffset: func(rotorPitchValue int) float64 {
-					return float64(cfg.TickLength)
-				},
-			},
-		),
+		impl: indicator.New(newConfig(cfg)),
+	}
+}
+
+func newConfig(cfg *IndicatorConfig) *indicator.Config {
+	return &indicator.Config{
+		Width:               cfg.Width,
+		Height:              cfg.Height,
+		TickLength:          cfg.TickLength,
+		MinorTickLength:     cfg.MinorTickLength,
+		LineWidth:           cfg.LineWidth,
+		Color:               cfg.Color,
+		BorderColor:         cfg.BorderColor,
+		Rect:                cfg.Rect,
+		MinValue:            minPitch,
+		MaxValue:            maxPitch,
+		MinFixedWindowValue: minPitch,
+		MaxFixedWindowValue: maxPitch,
+		MinTickStep:         pitchStep,
+		GetTickLength: func(rotorPitchValue int) float64 {
+			tickLen := float64(cfg.MinorTickLength)
+			if rotorPitchValue%2 != 0 {
+				tickLen = float64(cfg.TickLength)
+			}
+			return tickLen
+		},
+		MinSafeValue:    nil,
+		MaxAllowedValue: nil,
+		MinLabelStep:    2,
+		GetLabelOffset: func(rotorPitchValue int) float64 {
+			return float64(cfg.TickLength)
+		},
+		FontFace: cfg.FontFace,
 	}
 }
 
@@ -73,6 +78,11 @@ func (i *Indicator) GetRotorPitch() (rotorPitch float64) {
 	return i.impl.GetValue()
 }
 
+// Rebuild regenerates the indicator images for the new dimensions in cfg.
+func (i *Indicator) Rebuild(cfg *IndicatorConfig) {
+	i.impl.Rebuild(newConfig(cfg))
+}
+
 func (i *Indicator) GetImage() (img *ebiten.Image, isRedrawn bool) {
 	img, isRedrawn = i.impl.GetImage()
 	return
@@ -5,6 +5,7 @@ import (
 	"image/color"
 
 	"github.com/hajimehoshi/ebiten/v2"
+	"golang.org/x/image/font"
 
 	"github.com/dimchansky/dcs-hmd/gui/indicator"
 )
@@ -18,47 +19,51 @@ type IndicatorConfig struct {
 	Color           color.NRGBA
 	BorderColor     color.NRGBA
 	Rect            image.Rectangle
+	FontFace        font.Face
 }
 
 func NewIndicator(cfg *IndicatorConfig) *Indicator {
+	return &Indicator{
+		impl: indicator.New(newConfig(cfg)),
+	}
+}
+
+func newConfig(cfg *IndicatorConfig) *indicator.Config {
 	// Maximum allowed rotor RPM – 98%
 	// Minimum safe RPM in flight – 83%
 	minSafeRPM := 83
 	maxAllowedRPM := 98
 
-	return &Indicator{
-		impl: indicator.New(
-			&indicator.Config{
-				Width:               cfg.Width,
-				Height:              cfg.Height,
-				TickLength:          cfg.TickLength,
-				MinorTickLength:     cfg.MinorTickLength,
-				LineWidth:           cfg.LineWidth,
-				Color:               cfg.Color,
-				BorderColor:         cfg.BorderColor,
-				Rect:                cfg.Rect,
-				MinValue:            0,
-				MaxValue:            110,
-				MinFixedWindowValue: 80,
-				MaxFixedWindowValue: 100,
-				MinTickStep:         1,
-				GetTickLength: func(rpmValue int) float64 {
-					tickLen := float64(cfg.MinorTickLength / 2)
-					if rpmValue%10 == 0 {
-						tickLen = float64(cfg.TickLength)
-					} else if rpmValue%5 == 0 {
-						tickLen = float64(cfg.MinorTickLength)
-					}
-					return tickLen
-				},
-				MinSafeValue:    &minSafeRPM,
-				MaxAllowedValue: &maxAllowedRPM,
-				MinLabelStep:    10,
-				GetLabelOffset: func(rpmValue int) float64 {
-					return float64(cfg.TickLength)
-				},
-			},
-		),
+	return &indicator.Config{
+		Width:               cfg.Width,
+		Height:              cfg.Height,
+		TickLength:          cfg.TickLength,
+		MinorTickLength:     cfg.MinorTickLength,
+		LineWidth:           cfg.LineWidth,
+		Color:               cfg.Color,
+		BorderColor:         cfg.BorderColor,
+		Rect:                cfg.Rect,
+		MinValue:            0,
+		MaxValue:            110,
+		MinFixedWindowValue: 80,
+		MaxFixedWindowValue: 100,
+		MinTickStep:         1,
+		GetTickLength: func(rpmValue int) float64 {
+			tickLen := float64(cfg.MinorTickLength / 2)
+			if rpmValue%10 == 0 {
+				tickLen = float64(cfg.TickLength)
+			} else if rpmValue%5 == 0 {
+				tickLen = float64(cfg.MinorTickLength)
+			}
+			return tickLen
+		},
+		MinSafeValue:    &minSafeRPM,
+		MaxAllowedValue: &maxAllowedRPM,
+		MinLabelStep:    10,
+		GetLabelOffset: func(rpmValue int) float64 {
+			return float64(cfg.TickLength)
+		},
+		FontFace: cfg.FontFace,
 	}
 }
 
@@ -74,6 +79,11 @@ func (i *Indicator) GetRotorRPM() (rotorRPM float64) {
 	return i.impl.GetValue()
 }
 
+// Rebuild regenerates the indicator images for the new dimensions in cfg.
+func (i *Indicator) Rebuild(cfg *IndicatorConfig) {
+	i.impl.Rebuild(newConfig(cfg))
+}
+
 func (i *Indicator) GetImage() (img *ebiten.Image, isRedrawn bool) {
 	img, isRedrawn = i.impl.GetImage()
 	return
@@ -5,6 +5,7 @@ import (
 	"image/color"
 
 	"github.com/hajimehoshi/ebiten/v2"
+	"golang.org/x/image/font"
 
 	"github.com/dimchansky/dcs-hmd/gui/indicator"
 )
@@ -18,42 +19,46 @@ type IndicatorConfig struct {
 	Color           color.NRGBA
 	BorderColor     color.NRGBA
 	Rect            image.Rectangle
+	FontFace        font.Face
 }
 
 func NewIndicator(cfg *IndicatorConfig) *Indicator {
 	return &Indicator{
-		impl: indicator.New(
-			&indicator.Config{
-				Width:               cfg.Width,
-				Height:              cfg.Height,
-				TickLength:          cfg.TickLength,
-				MinorTickLength:     cfg.MinorTickLength,
-				LineWidth:           cfg.LineWidth,
-				Color:               cfg.Color,
-				BorderColor:         cfg.BorderColor,
-				Rect:                cfg.Rect,
-				MinValue:            -30,
-				MaxValue:            30,
-				MinFixedWindowValue: -8,
-				MaxFixedWindowValue: 8,
-				MinTickStep:         1,
-				GetTickLength: func(rpmValue int) float64 {
-					tickLen := float64(cfg.MinorTickLength / 2)
-					if rpmValue%10 == 0 {
-						tickLen = float64(cfg.TickLength)
-					} else if rpmValue%5 == 0 {
-						tickLen = float64(cfg.MinorTickLength)
-					}
-					return tickLen
-				},
-				MinSafeValue:    nil,
-				MaxAllowedValue: nil,
-				MinLabelStep:    5,
-				GetLabelOffset: func(rpmValue int) float64 {
-					return float64(cfg.TickLength)
-				},
-			},
-		),
+		impl: indicator.New(newConfig(cfg)),
+	}
+}
+
+func newConfig(cfg *IndicatorConfig) *indicator.Config {
+	return &indicator.Config{
+		Width:               cfg.Width,
+		Height:              cfg.Height,
+		TickLength:          cfg.TickLength,
+		MinorTickLength:     cfg.MinorTickLength,
+		LineWidth:           cfg.LineWidth,
+		Color:               cfg.Color,
+		BorderColor:         cfg.BorderColor,
+		Rect:                cfg.Rect,
+		MinValue:            -30,
+		MaxValue:            30,
+		MinFixedWindowValue: -8,
+		MaxFixedWindowValue: 8,
+		MinTickStep:         1,
+		GetTickLength: func(rpmValue int) float64 {
+			tickLen := float64(cfg.MinorTickLength / 2)
+			if rpmValue%10 == 0 {
+				tickLen = float64(cfg.TickLength)
+			} else if rpmValue%5 == 0 {
+				tickLen = float64(cfg.MinorTickLength)
+			}
+			return tickLen
+		},
+		MinSafeValue:    nil,
+		MaxAllowedValue: nil,
+		MinLabelStep:    5,
+		GetLabelOffset: func(rpmValue int) float64 {
+			return float64(cfg.TickLength)
+		},
+		FontFace: cfg.FontFace,
 	}
 }
 
@@ -69,6 +74,11 @@ func (i *Indicator) GetVerticalVelocity() (verticalVelocity float64) {
 	return i.impl.GetValue()
 }
 
+// Rebuild regenerates the indicator images for the new dimensions in cfg.
+func (i *Indicator) Rebuild(cfg *IndicatorConfig) {
+	i.impl.Rebuild(newConfig(cfg))
+}
+
 func (i *Indicator) GetImage() (img *ebiten.Image, isRedrawn bool) {
 	img, isRedrawn = i.impl.GetImage()
 	return
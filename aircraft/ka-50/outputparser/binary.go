@@ -0,0 +1,133 @@
+package outputparser
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// The binary frame is the compact alternative to the text message, all the numbers are little-endian:
+//
+//	offset  size  field
+//	0       1     BinaryMagic, the byte never starts a text message
+//	1       1     version, BinaryVersion
+//	2       4     simulation ID, uint32
+//	6       4     sequence number, uint32
+//	10      1     number of values N
+//	11      6*N   argument ID uint16 and value float32 pairs
+//	11+6*N  2     Fletcher-16 checksum of all the previous bytes, uint16
+const (
+	BinaryMagic   = 0xDC
+	BinaryVersion = 1
+
+	binaryHeaderSize   = 11
+	binaryValueSize    = 6
+	binaryChecksumSize = 2
+
+	// maxBinaryValues is the maximal number of values in the frame.
+	maxBinaryValues = math.MaxUint8
+)
+
+var (
+	errBinaryTooShort = errors.New("binary frame is too short")
+	errBinaryChecksum = errors.New("binary frame checksum mismatch")
+)
+
+// BinaryFrame is the decoded binary frame.
+type BinaryFrame struct {
+	Version uint8
+	SimID   uint32
+	Seq     uint32
+	Values  []BinaryValue
+}
+
+// BinaryValue is the value of the argument in the binary frame.
+type BinaryValue struct {
+	Arg   uint16
+	Value float32
+}
+
+// IsBinary reports whether the message is a binary frame.
+func IsBinary(msg []byte) bool {
+	return len(msg) > 0 && msg[0] == BinaryMagic
+}
+
+// DecodeBinaryFrame decodes the binary frame into f, the values slice of f is reused.
+func DecodeBinaryFrame(msg []byte, f *BinaryFrame) error {
+	if !IsBinary(msg) {
+		return errors.New("not a binary frame")
+	}
+
+	if len(msg) < binaryHeaderSize+binaryChecksumSize {
+		return errBinaryTooShort
+	}
+
+	if version := msg[1]; version != BinaryVersion {
+		return fmt.Errorf("unsupported binary frame version: %d", version)
+	}
+
+	n := int(msg[10])
+
+	size := binaryHeaderSize + n*binaryValueSize + binaryChecksumSize
+	if len(msg) < size {
+		return errBinaryTooShort
+	}
+
+	if len(msg) > size {
+		return fmt.Errorf("binary frame has %d extra bytes", len(msg)-size)
+	}
+
+	checksumPos := size - binaryChecksumSize
+	if fletcher16(msg[:checksumPos]) != binary.LittleEndian.Uint16(msg[checksumPos:]) {
+		return errBinaryChecksum
+	}
+
+	f.Version = msg[1]
+	f.SimID = binary.LittleEndian.Uint32(msg[2:])
+	f.Seq = binary.LittleEndian.Uint32(msg[6:])
+	f.Values = f.Values[:0]
+
+	for pos := binaryHeaderSize; pos < checksumPos; pos += binaryValueSize {
+		f.Values = append(f.Values, BinaryValue{
+			Arg:   binary.LittleEndian.Uint16(msg[pos:]),
+			Value: math.Float32frombits(binary.LittleEndian.Uint32(msg[pos+2:])),
+		})
+	}
+
+	return nil
+}
+
+// AppendBinaryFrame appends the encoded frame to dst, the version of the frame is ignored.
+func AppendBinaryFrame(dst []byte, f *BinaryFrame) ([]byte, error) {
+	if len(f.Values) > maxBinaryValues {
+		return dst, fmt.Errorf("too many values in binary frame: %d", len(f.Values))
+	}
+
+	start := len(dst)
+
+	dst = append(dst, BinaryMagic, BinaryVersion)
+	dst = binary.LittleEndian.AppendUint32(dst, f.SimID)
+	dst = binary.LittleEndian.AppendUint32(dst, f.Seq)
+	dst = append(dst, uint8(len(f.Values)))
+
+	for _, v := range f.Values {
+		dst = binary.LittleEndian.AppendUint16(dst, v.Arg)
+		dst = binary.LittleEndian.AppendUint32(dst, math.Float32bits(v.Value))
+	}
+
+	return binary.LittleEndian.AppendUint16(dst, fletcher16(dst[start:])), nil
+}
+
+// fletcher16 returns the Fletcher-16 checksum, the exporter calculates it without bitwise operations
+// which Lua 5.1 doesn't have.
+func fletcher16(data []byte) uint16 {
+	var sum1, sum2 uint16
+
+	for _, b := range data {
+		sum1 = (sum1 + uint16(b)) % 255
+		sum2 = (sum2 + sum1) % 255
+	}
+
+	return sum2<<8 | sum1
+}
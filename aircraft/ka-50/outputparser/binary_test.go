@@ -0,0 +1,141 @@
+package outputparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_fletcher16(t *testing.T) {
+	tests := []struct {
+		data string
+		want uint16
+	}{
+		{"", 0},
+		{"abcde", 0xC8F0},
+		{"abcdef", 0x2057},
+		{"abcdefgh", 0x0627},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.data, func(t *testing.T) {
+			require.Equal(t, tt.want, fletcher16([]byte(tt.data)))
+		})
+	}
+}
+
+func TestBinaryFrame_RoundTrip(t *testing.T) {
+	f := BinaryFrame{
+		SimID: 0x637beb27,
+		Seq:   42,
+		Values: []BinaryValue{
+			{Arg: 53, Value: 0.9362},
+			{Arg: 52, Value: 0.7792},
+			{Arg: 24, Value: -1},
+		},
+	}
+
+	msg, err := AppendBinaryFrame(nil, &f)
+	require.NoError(t, err)
+	require.Len(t, msg, binaryHeaderSize+3*binaryValueSize+binaryChecksumSize)
+	require.True(t, IsBinary(msg))
+
+	var decoded BinaryFrame
+	require.NoError(t, DecodeBinaryFrame(msg, &decoded))
+
+	f.Version = BinaryVersion
+	require.Equal(t, f, decoded)
+}
+
+func TestDecodeBinaryFrame_Errors(t *testing.T) {
+	valid, err := AppendBinaryFrame(nil, &BinaryFrame{SimID: 1, Seq: 2, Values: []BinaryValue{{Arg: 52, Value: 0.5}}})
+	require.NoError(t, err)
+
+	withVersion := func(version byte) []byte {
+		msg := append([]byte(nil), valid...)
+		msg[1] = version
+
+		return msg
+	}
+
+	corrupted := append([]byte(nil), valid...)
+	corrupted[binaryHeaderSize+2]++
+
+	tests := []struct {
+		name string
+		msg  []byte
+	}{
+		{"text message", []byte("637beb27*52=0.7792")},
+		{"too short", valid[:binaryHeaderSize]},
+		{"truncated values", valid[:len(valid)-1]},
+		{"extra bytes", append(append([]byte(nil), valid...), 0)},
+		{"unsupported version", withVersion(BinaryVersion + 1)},
+		{"checksum mismatch", corrupted},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var f BinaryFrame
+			require.Error(t, DecodeBinaryFrame(tt.msg, &f))
+		})
+	}
+}
+
+func TestOutputParser_HandleBinaryMessage(t *testing.T) {
+	var values recordingValuesSetter
+
+	p := New(&values)
+
+	msg, err := AppendBinaryFrame(nil, &BinaryFrame{
+		SimID: 0x637beb27,
+		Values: []BinaryValue{
+			{Arg: 53, Value: 0.5},
+			{Arg: 52, Value: 0.75},
+			{Arg: 24, Value: 0.5},
+			{Arg: 1000, Value: 1},
+		},
+	})
+	require.NoError(t, err)
+
+	p.HandleMessage(msg)
+
+	require.Equal(t, recordingValuesSetter{rotorPitch: 8, rotorRPM: 82.5, verticalVelocity: 15}, values)
+
+	stats := p.Stats()
+	require.Equal(t, uint64(0x637beb27), stats.SimID)
+	require.Equal(t, uint64(1), stats.UnknownArgs)
+	require.Zero(t, stats.ParseErrors)
+
+	p.HandleMessage(msg[:len(msg)-1])
+	require.Equal(t, uint64(1), p.Stats().ParseErrors)
+}
+
+func BenchmarkOutputParser_HandleBinaryMessage(b *testing.B) {
+	p := New(&recordingValuesSetter{})
+
+	msg, err := AppendBinaryFrame(nil, &BinaryFrame{
+		SimID: 0x637beb27,
+		Values: []BinaryValue{
+			{Arg: 53, Value: 0.9362}, {Arg: 52, Value: 0.7792}, {Arg: 24, Value: -0.01},
+			{Arg: 1000, Value: 1.2345}, {Arg: 1001, Value: 1.2345}, {Arg: 1002, Value: 1.2345}, {Arg: 1003, Value: 1.2345},
+		},
+	})
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		p.HandleMessage(msg)
+	}
+}
+
+type recordingValuesSetter struct {
+	rotorPitch, rotorRPM, verticalVelocity float64
+}
+
+func (s *recordingValuesSetter) SetRotorPitch(val float64)       { s.rotorPitch = val }
+func (s *recordingValuesSetter) SetRotorRPM(val float64)         { s.rotorRPM = val }
+func (s *recordingValuesSetter) SetVerticalVelocity(val float64) { s.verticalVelocity = val }
@@ -0,0 +1,112 @@
+package outputparser
+
+import "math"
+
+// FlightData holds the sim-level flight data the exporter reads with the LoGet* functions. Unlike the cockpit
+// arguments, the values are in physical units and are exported for every airframe.
+type FlightData struct {
+	// IndicatedAirspeed is in m/s.
+	IndicatedAirspeed float64
+	// AltitudeAGL is the altitude above ground level in meters.
+	AltitudeAGL float64
+	// Velocity is the velocity vector in the world frame in m/s: X points north, Y up and Z east.
+	Velocity Vec3
+	// Acceleration is the acceleration in the body frame in G: X points forward, Y up and Z right,
+	// so Y is the G-load.
+	Acceleration Vec3
+	// AngleOfAttack is in radians.
+	AngleOfAttack float64
+	// SlipBall is the position of the slip ball from -1 (full left) to 1 (full right).
+	SlipBall float64
+	// Heading is the true heading in radians from 0 to 2π, it turns the world frame Velocity to the aircraft heading.
+	Heading float64
+	// Position is the position in the world frame in meters, the axes are the same as of Velocity. The binary
+	// protocol carries it with about 5 cm precision far from the map origin.
+	Position Vec3
+}
+
+// GroundVelocity returns the horizontal velocity in m/s turned to the aircraft heading: forward is along
+// the heading and right is perpendicular to it. It is the drift of the aircraft over the ground in the hover.
+func (fd *FlightData) GroundVelocity() (forward, right float64) {
+	return HeadingFrame(fd.Velocity.X, fd.Velocity.Z, fd.Heading)
+}
+
+// HeadingFrame turns the horizontal vector of the world frame (north and east components) to the heading frame:
+// forward is along the heading and right is perpendicular to it.
+func HeadingFrame(north, east, heading float64) (forward, right float64) {
+	sin, cos := math.Sincos(heading)
+
+	return north*cos + east*sin, east*cos - north*sin
+}
+
+// Vec3 is the vector of the flight data.
+type Vec3 struct {
+	X, Y, Z float64
+}
+
+// FlightDataSetter is implemented by the ValuesSetter that shows the flight data. The parser calls SetFlightData
+// once per message with the changed values, so that the vector components are consistent.
+type FlightDataSetter interface {
+	SetFlightData(fd FlightData)
+}
+
+func (m multiValuesSetter) SetFlightData(fd FlightData) {
+	for _, s := range m {
+		if fds, ok := s.(FlightDataSetter); ok {
+			fds.SetFlightData(fd)
+		}
+	}
+}
+
+// Channel IDs of the flight data, see DCSHMD.FlightDataChannels in Core.lua. They are consecutive and above
+// the cockpit argument numbers.
+const (
+	indicatedAirspeedID = 10001 + iota
+	altitudeAGLID
+	velocityXID
+	velocityYID
+	velocityZID
+	accelerationXID
+	accelerationYID
+	accelerationZID
+	angleOfAttackID
+	slipBallID
+	headingID
+	positionXID
+	positionYID
+	positionZID
+)
+
+// set sets the value of the flight data channel.
+func (fd *FlightData) set(channel int, val float64) {
+	switch channel {
+	case indicatedAirspeedChannel:
+		fd.IndicatedAirspeed = val
+	case altitudeAGLChannel:
+		fd.AltitudeAGL = val
+	case velocityXChannel:
+		fd.Velocity.X = val
+	case velocityYChannel:
+		fd.Velocity.Y = val
+	case velocityZChannel:
+		fd.Velocity.Z = val
+	case accelerationXChannel:
+		fd.Acceleration.X = val
+	case accelerationYChannel:
+		fd.Acceleration.Y = val
+	case accelerationZChannel:
+		fd.Acceleration.Z = val
+	case angleOfAttackChannel:
+		fd.AngleOfAttack = val
+	case slipBallChannel:
+		fd.SlipBall = val
+	case headingChannel:
+		fd.Heading = val
+	case positionXChannel:
+		fd.Position.X = val
+	case positionYChannel:
+		fd.Position.Y = val
+	case positionZChannel:
+		fd.Position.Z = val
+	}
+}
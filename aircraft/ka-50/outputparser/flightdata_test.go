@@ -0,0 +1,106 @@
+package outputparser_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimchansky/dcs-hmd/aircraft/ka-50/outputparser"
+)
+
+type flightDataSetter struct {
+	emptyValuesSetter
+	calls []outputparser.FlightData
+}
+
+func (s *flightDataSetter) SetFlightData(fd outputparser.FlightData) { s.calls = append(s.calls, fd) }
+
+func TestOutputParser_FlightData(t *testing.T) {
+	s := &flightDataSetter{}
+	p := outputparser.New(s)
+
+	p.HandleMessage([]byte("637beb27-0*10001=41.67:10002=3.50:10003=1.000:10004=-0.500:10005=2.000:" +
+		"10006=0.100:10007=1.200:10008=-0.050:10009=0.0500:10010=-0.2500:10011=4.7124:10012=-284500.25:10013=12.50:10014=683200.75:52=0.7792\n"))
+
+	want := outputparser.FlightData{
+		IndicatedAirspeed: 41.67,
+		AltitudeAGL:       3.5,
+		Velocity:          outputparser.Vec3{X: 1, Y: -0.5, Z: 2},
+		Acceleration:      outputparser.Vec3{X: 0.1, Y: 1.2, Z: -0.05},
+		AngleOfAttack:     0.05,
+		SlipBall:          -0.25,
+		Heading:           4.7124,
+		Position:          outputparser.Vec3{X: -284500.25, Y: 12.5, Z: 683200.75},
+	}
+	require.Equal(t, []outputparser.FlightData{want}, s.calls)
+
+	// only the changed values are sent, the others are kept
+	p.HandleMessage([]byte("637beb27-1*10004=0.250:10010=0.1000\n"))
+
+	want.Velocity.Y = 0.25
+	want.SlipBall = 0.1
+	require.Equal(t, want, s.calls[1])
+
+	// the setter is not called without the flight data
+	p.HandleMessage([]byte("637beb27-2*52=0.7792\n"))
+	require.Len(t, s.calls, 2)
+
+	msg, err := outputparser.AppendBinaryFrame(nil, &outputparser.BinaryFrame{
+		SimID:  0x637beb27,
+		Seq:    3,
+		Values: []outputparser.BinaryValue{{Arg: 10002, Value: 10}, {Arg: 10007, Value: 2}},
+	})
+	require.NoError(t, err)
+
+	p.HandleMessage(msg)
+
+	want.AltitudeAGL = 10
+	want.Acceleration.Y = 2
+	require.Equal(t, want, s.calls[2])
+
+	stats := p.Stats()
+	require.Zero(t, stats.ParseErrors)
+	require.Zero(t, stats.UnknownArgs)
+}
+
+func TestMultiValuesSetter_FlightData(t *testing.T) {
+	first := &flightDataSetter{}
+	second := &flightDataSetter{}
+
+	p := outputparser.New(outputparser.MultiValuesSetter(first, emptyValuesSetter{}, second))
+	p.HandleMessage([]byte("637beb27*10001=20.00\n"))
+
+	want := []outputparser.FlightData{{IndicatedAirspeed: 20}}
+	require.Equal(t, want, first.calls)
+	require.Equal(t, want, second.calls)
+}
+
+func TestFlightData_GroundVelocity(t *testing.T) {
+	tests := []struct {
+		name     string
+		velocity outputparser.Vec3
+		heading  float64
+		forward  float64
+		right    float64
+	}{
+		{"north heading north", outputparser.Vec3{X: 5}, 0, 5, 0},
+		{"east heading north", outputparser.Vec3{Z: 3}, 0, 0, 3},
+		{"north heading east", outputparser.Vec3{X: 5}, math.Pi / 2, 0, -5},
+		{"east heading east", outputparser.Vec3{Z: 3}, math.Pi / 2, 3, 0},
+		{"south heading west", outputparser.Vec3{X: -2}, 3 * math.Pi / 2, 0, -2},
+		{"north-east heading north-east", outputparser.Vec3{X: 1, Z: 1}, math.Pi / 4, math.Sqrt2, 0},
+		{"vertical velocity is ignored", outputparser.Vec3{Y: 10}, 1, 0, 0},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			fd := outputparser.FlightData{Velocity: tt.velocity, Heading: tt.heading}
+
+			forward, right := fd.GroundVelocity()
+			require.InDelta(t, tt.forward, forward, 1e-9)
+			require.InDelta(t, tt.right, right, 1e-9)
+		})
+	}
+}
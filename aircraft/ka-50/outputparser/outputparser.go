@@ -2,9 +2,15 @@ package outputparser
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"log/slog"
 	"strconv"
+	"sync"
+	"time"
 	"unsafe"
+
+	"github.com/dimchansky/dcs-hmd/utils"
 )
 
 type ValuesSetter interface {
@@ -13,22 +19,199 @@ type ValuesSetter interface {
 	SetRotorRPM(val float64)
 }
 
+// MultiValuesSetter returns a ValuesSetter that duplicates its calls to all the provided setters,
+// similar to io.MultiWriter.
+func MultiValuesSetter(setters ...ValuesSetter) ValuesSetter {
+	all := make(multiValuesSetter, len(setters))
+	copy(all, setters)
+
+	return all
+}
+
+type multiValuesSetter []ValuesSetter
+
+func (m multiValuesSetter) SetVerticalVelocity(val float64) {
+	for _, s := range m {
+		s.SetVerticalVelocity(val)
+	}
+}
+
+func (m multiValuesSetter) SetRotorPitch(val float64) {
+	for _, s := range m {
+		s.SetRotorPitch(val)
+	}
+}
+
+func (m multiValuesSetter) SetRotorRPM(val float64) {
+	for _, s := range m {
+		s.SetRotorRPM(val)
+	}
+}
+
 func New(s ValuesSetter) *OutputParser {
-	return &OutputParser{s: s}
+	p := &OutputParser{s: s, malformedLog: utils.NewRateLimiter(malformedLogInterval, malformedLogBurst)}
+	p.fds, _ = s.(FlightDataSetter)
+
+	for i, ch := range channels {
+		p.stats.Channels[i] = ChannelStats{Arg: ch.arg, Name: ch.name, FlightData: ch.arg >= indicatedAirspeedID}
+	}
+
+	return p
 }
 
 type OutputParser struct {
 	s ValuesSetter
+	// fds is s if it shows the flight data, otherwise nil
+	fds FlightDataSetter
+
+	statsMutex sync.Mutex
+	stats      parserStats
+
+	malformedLog *utils.RateLimiter
+
+	// frame is reused to decode the binary frames without allocations
+	frame BinaryFrame
+	seq   sequenceTracker
+
+	// flightData holds the latest flight data, flightDataChanged reports whether it is changed by the message
+	flightData        FlightData
+	flightDataChanged bool
 }
 
+const (
+	// malformedLogInterval and malformedLogBurst limit the rate of the malformed messages log messages.
+	malformedLogInterval = time.Minute
+	malformedLogBurst    = 10
+)
+
+// errUnexpectedText is reported when the message has the text that is not a sequence of arguments.
+var errUnexpectedText = errors.New("unexpected text instead of argument")
+
 const (
 	verticalVelocity = 24
 	rotorRPMArg      = 52
 	rotorPitchArg    = 53
 )
 
-// HandleMessage implements udplistener.MessageHandler interface.
+// channel indexes in the statistics
+const (
+	verticalVelocityChannel = iota
+	rotorRPMChannel
+	rotorPitchChannel
+	indicatedAirspeedChannel
+	altitudeAGLChannel
+	velocityXChannel
+	velocityYChannel
+	velocityZChannel
+	accelerationXChannel
+	accelerationYChannel
+	accelerationZChannel
+	angleOfAttackChannel
+	slipBallChannel
+	headingChannel
+	positionXChannel
+	positionYChannel
+	positionZChannel
+	channelCount
+)
+
+var channels = [channelCount]struct {
+	arg  uint64
+	name string
+}{
+	verticalVelocityChannel: {verticalVelocity, "vertical velocity"},
+	rotorRPMChannel:         {rotorRPMArg, "rotor RPM"},
+	rotorPitchChannel:       {rotorPitchArg, "rotor pitch"},
+
+	indicatedAirspeedChannel: {indicatedAirspeedID, "indicated airspeed"},
+	altitudeAGLChannel:       {altitudeAGLID, "altitude AGL"},
+	velocityXChannel:         {velocityXID, "velocity X"},
+	velocityYChannel:         {velocityYID, "velocity Y"},
+	velocityZChannel:         {velocityZID, "velocity Z"},
+	accelerationXChannel:     {accelerationXID, "acceleration X"},
+	accelerationYChannel:     {accelerationYID, "acceleration Y"},
+	accelerationZChannel:     {accelerationZID, "acceleration Z"},
+	angleOfAttackChannel:     {angleOfAttackID, "angle of attack"},
+	slipBallChannel:          {slipBallID, "slip ball"},
+	headingChannel:           {headingID, "heading"},
+	positionXChannel:         {positionXID, "position X"},
+	positionYChannel:         {positionYID, "position Y"},
+	positionZChannel:         {positionZID, "position Z"},
+}
+
+// Stats holds the parser counters.
+type Stats struct {
+	Messages uint64
+	// ParseErrors counts the messages and values that can't be parsed.
+	ParseErrors uint64
+	// UnknownArgs counts the values of the arguments the parser doesn't know, LastUnknownArg is the latest of them.
+	UnknownArgs    uint64
+	LastUnknownArg uint64
+	// SimID is the simulation ID of the latest message with the simulation prefix, it is zero if there was none.
+	SimID    uint64
+	Channels []ChannelStats
+	// Sequence holds the counters of the dropped and lost packets detected by the sequence numbers.
+	Sequence SequenceStats
+}
+
+// ChannelStats holds the counters of the known argument.
+type ChannelStats struct {
+	Arg  uint64
+	Name string
+	// FlightData reports whether the channel is the flight data one rather than the cockpit argument.
+	FlightData bool
+	Updates    uint64
+	// LastUpdate is the time the value was set last time, it is zero if the value has never been set.
+	LastUpdate time.Time
+}
+
+// parserStats is Stats with the fixed set of channels, so that the counting doesn't allocate.
+type parserStats struct {
+	Messages       uint64
+	ParseErrors    uint64
+	UnknownArgs    uint64
+	LastUnknownArg uint64
+	SimID          uint64
+	Channels       [channelCount]ChannelStats
+	Sequence       SequenceStats
+}
+
+// Stats returns the current values of the parser counters, it is thread-safe.
+func (p *OutputParser) Stats() Stats {
+	m := &p.statsMutex
+	m.Lock()
+	s := p.stats
+	m.Unlock()
+
+	return Stats{
+		Messages:       s.Messages,
+		ParseErrors:    s.ParseErrors,
+		UnknownArgs:    s.UnknownArgs,
+		LastUnknownArg: s.LastUnknownArg,
+		SimID:          s.SimID,
+		Channels:       s.Channels[:],
+		Sequence:       s.Sequence,
+	}
+}
+
+// HandleMessage implements udplistener.MessageHandler interface. The message is either the text message
+// or the binary frame, they are told apart by the first byte.
 func (p *OutputParser) HandleMessage(msg []byte) {
+	m := &p.statsMutex
+	m.Lock()
+	defer m.Unlock()
+
+	defer p.flushFlightData()
+
+	stats := &p.stats
+	stats.Messages++
+
+	if IsBinary(msg) {
+		p.handleBinary(msg)
+		return
+	}
+
+	origMsg := msg
 	pSimPrefix := parseSimPrefix(msg)
 	msg = pSimPrefix.Rest
 
@@ -38,6 +221,13 @@ func (p *OutputParser) HandleMessage(msg []byte) {
 		if len(msg) >= 1 && msg[0] == ':' {
 			msg = msg[1:]
 		}
+	} else {
+		prefix := &pSimPrefix.Result
+		stats.SimID = prefix.SimID
+
+		if prefix.HasSeq && !p.seq.check(prefix.SimID, prefix.Seq, time.Now(), &stats.Sequence) {
+			return
+		}
 	}
 
 	for {
@@ -45,6 +235,13 @@ func (p *OutputParser) HandleMessage(msg []byte) {
 		msg = pArg.Rest
 
 		if !pArg.Ok {
+			// the rest of the message must be empty or the line terminator
+			if err := pArg.Err; err != nil {
+				p.malformed(origMsg, err)
+			} else if len(bytes.TrimRight(msg, "\r\n")) > 0 {
+				p.malformed(origMsg, errUnexpectedText)
+			}
+
 			return
 		}
 
@@ -53,30 +250,134 @@ func (p *OutputParser) HandleMessage(msg []byte) {
 		msg = pVal.Rest
 
 		if !pVal.Ok {
+			p.malformed(origMsg, pVal.Err)
 			return
 		}
 
+		channel, ok := channelOf(arg)
+		if !ok {
+			stats.UnknownArgs++
+			stats.LastUnknownArg = arg
+
+			continue
+		}
+
 		valBs := pVal.Result
 
-		switch arg {
-		case verticalVelocity: // vertical velocity
-			handleVerticalVelocity(p.s, valBs)
+		val, err := strconv.ParseFloat(*(*string)(unsafe.Pointer(&valBs)), 64)
+		if err != nil {
+			p.malformed(origMsg, fmt.Errorf("parsing value of argument %d: %w", arg, err))
+			continue
+		}
+
+		p.setValue(channel, val)
+	}
+}
+
+// handleBinary handles the binary frame, it must be called with the stats mutex locked.
+func (p *OutputParser) handleBinary(msg []byte) {
+	f := &p.frame
+	if err := DecodeBinaryFrame(msg, f); err != nil {
+		p.malformed(msg, err)
+		return
+	}
+
+	stats := &p.stats
+	stats.SimID = uint64(f.SimID)
 
-		case rotorRPMArg: // rotor RPM
-			handleRotorRPM(p.s, valBs)
+	if !p.seq.check(stats.SimID, f.Seq, time.Now(), &stats.Sequence) {
+		return
+	}
 
-		case rotorPitchArg: // rotor pitch
-			handleRotorPitch(p.s, valBs)
+	for _, v := range f.Values {
+		channel, ok := channelOf(uint64(v.Arg))
+		if !ok {
+			stats.UnknownArgs++
+			stats.LastUnknownArg = uint64(v.Arg)
+
+			continue
 		}
+
+		p.setValue(channel, float64(v.Value))
 	}
 }
 
-func handleVerticalVelocity(s ValuesSetter, valBs []byte) {
-	val, err := strconv.ParseFloat(*(*string)(unsafe.Pointer(&valBs)), 64)
-	if err != nil {
+// channelOf returns the channel of the argument, it returns false if the argument is unknown.
+func channelOf(arg uint64) (int, bool) {
+	switch arg {
+	case verticalVelocity: // vertical velocity
+		return verticalVelocityChannel, true
+
+	case rotorRPMArg: // rotor RPM
+		return rotorRPMChannel, true
+
+	case rotorPitchArg: // rotor pitch
+		return rotorPitchChannel, true
+
+	default:
+		if arg >= indicatedAirspeedID && arg <= positionZID { // flight data
+			return indicatedAirspeedChannel + int(arg-indicatedAirspeedID), true
+		}
+
+		return 0, false
+	}
+}
+
+// setValue sets the exported value of the channel, it must be called with the stats mutex locked.
+func (p *OutputParser) setValue(channel int, val float64) {
+	switch channel {
+	case verticalVelocityChannel:
+		handleVerticalVelocity(p.s, val)
+
+	case rotorRPMChannel:
+		handleRotorRPM(p.s, val)
+
+	case rotorPitchChannel:
+		handleRotorPitch(p.s, val)
+
+	default:
+		p.flightData.set(channel, val)
+		p.flightDataChanged = true
+	}
+
+	ch := &p.stats.Channels[channel]
+	ch.Updates++
+	ch.LastUpdate = time.Now()
+}
+
+// flushFlightData passes the flight data changed by the message to the setter, it must be called with the stats
+// mutex locked.
+func (p *OutputParser) flushFlightData() {
+	if !p.flightDataChanged {
+		return
+	}
+
+	p.flightDataChanged = false
+
+	if p.fds != nil {
+		p.fds.SetFlightData(p.flightData)
+	}
+}
+
+// malformed counts the parse error and logs the message, the log messages are rate-limited.
+// It must be called with the stats mutex locked.
+func (p *OutputParser) malformed(msg []byte, err error) {
+	p.stats.ParseErrors++
+
+	allowed, suppressed := p.malformedLog.Allow(time.Now())
+	if !allowed {
 		return
 	}
 
+	args := []any{"message", string(msg), "error", err}
+	if suppressed > 0 {
+		args = append(args, "suppressed", suppressed)
+	}
+
+	slog.Warn("malformed message", args...)
+}
+
+func handleVerticalVelocity(s ValuesSetter, val float64) {
 	const (
 		minVal              = -1.0
 		maxVal              = 1.0
@@ -87,23 +388,13 @@ func handleVerticalVelocity(s ValuesSetter, valBs []byte) {
 	s.SetVerticalVelocity((val-minVal)/(maxVal-minVal)*(maxVerticalVelocity-minVerticalVelocity) + minVerticalVelocity)
 }
 
-func handleRotorRPM(s ValuesSetter, valBs []byte) {
-	val, err := strconv.ParseFloat(*(*string)(unsafe.Pointer(&valBs)), 64)
-	if err != nil {
-		return
-	}
-
+func handleRotorRPM(s ValuesSetter, val float64) {
 	const maxRotorRPM = 110.0
 
 	s.SetRotorRPM(val * maxRotorRPM)
 }
 
-func handleRotorPitch(s ValuesSetter, valBs []byte) {
-	val, err := strconv.ParseFloat(*(*string)(unsafe.Pointer(&valBs)), 64)
-	if err != nil {
-		return
-	}
-
+func handleRotorPitch(s ValuesSetter, val float64) {
 	const (
 		maxRotorPitch = 15.0
 		minRotorPitch = 1.0
@@ -112,7 +403,14 @@ func handleRotorPitch(s ValuesSetter, valBs []byte) {
 	s.SetRotorPitch(val*(maxRotorPitch-minRotorPitch) + minRotorPitch)
 }
 
-func parseSimPrefix(msg []byte) parserResult[uint64] {
+// simPrefix is the prefix of the text message: SimID(hex) ['-' Seq(hex)] '*'.
+type simPrefix struct {
+	SimID  uint64
+	Seq    uint32
+	HasSeq bool
+}
+
+func parseSimPrefix(msg []byte) parserResult[simPrefix] {
 	rest := msg
 
 	var tmp []byte
@@ -123,14 +421,32 @@ func parseSimPrefix(msg []byte) parserResult[uint64] {
 		tmp = rest[:pos]
 		rest = rest[pos+1:]
 	} else {
-		return parseErr[uint64](msg, nil)
+		return parseErr[simPrefix](msg, nil)
 	}
 
-	if tmpUint, err := strconv.ParseUint(*(*string)(unsafe.Pointer(&tmp)), 16, 64); err != nil {
-		return parseErr[uint64](msg, fmt.Errorf("parsing `%s` into field Sim(hex): %w", *(*string)(unsafe.Pointer(&tmp)), err))
-	} else {
-		return parseOk[uint64](rest, tmpUint)
+	var prefix simPrefix
+
+	// Take after '-' as Seq, the messages of older exporters have no sequence number
+	if pos := bytes.IndexByte(tmp, '-'); pos >= 0 {
+		seqBs := tmp[pos+1:]
+		tmp = tmp[:pos]
+
+		seq, err := strconv.ParseUint(*(*string)(unsafe.Pointer(&seqBs)), 16, 32)
+		if err != nil {
+			return parseErr[simPrefix](msg, fmt.Errorf("parsing `%s` into field Seq(hex): %w", *(*string)(unsafe.Pointer(&seqBs)), err))
+		}
+
+		prefix.Seq, prefix.HasSeq = uint32(seq), true
 	}
+
+	simID, err := strconv.ParseUint(*(*string)(unsafe.Pointer(&tmp)), 16, 64)
+	if err != nil {
+		return parseErr[simPrefix](msg, fmt.Errorf("parsing `%s` into field Sim(hex): %w", *(*string)(unsafe.Pointer(&tmp)), err))
+	}
+
+	prefix.SimID = simID
+
+	return parseOk(rest, prefix)
 }
 
 // parse: Arg(uint) '='
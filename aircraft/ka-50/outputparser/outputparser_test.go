@@ -1,9 +1,13 @@
 package outputparser_test
 
 import (
+	"bytes"
+	"log/slog"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"github.com/dimchansky/dcs-hmd/aircraft/ka-50/outputparser"
 	mocks "github.com/dimchansky/dcs-hmd/internal/mocks/aircraft/ka-50/outputparser"
@@ -48,6 +52,9 @@ func TestOutputParser_HandleMessage(t *testing.T) {
 		{"637beb27*52=0.7792:1000='53=0.1:52=0.2'\r\n", nil, pFloat64(85.712), nil},
 		{"637beb27*53=0.9362:1000='53=0.1:52=0.2'\r\n", pFloat64(14.1068), nil, nil},
 
+		{"637beb27-2a*53=0.9362:52=0.7792\n", pFloat64(14.1068), pFloat64(85.712), nil},
+		{"637beb27-0*52=0.7792\n", nil, pFloat64(85.712), nil},
+
 		{":53=0.9362:52=0.7792\n", pFloat64(14.1068), pFloat64(85.712), nil},
 		{":52=0.7792\n", nil, pFloat64(85.712), nil},
 		{":53=0.9362\n", pFloat64(14.1068), nil, nil},
@@ -99,6 +106,114 @@ func TestOutputParser_HandleMessage(t *testing.T) {
 	}
 }
 
+func TestMultiValuesSetter(t *testing.T) {
+	first := &mocks.ValuesSetter{}
+	second := &mocks.ValuesSetter{}
+
+	for _, m := range []*mocks.ValuesSetter{first, second} {
+		m.On("SetRotorPitch", 14.1068)
+		m.On("SetRotorRPM", 85.712)
+	}
+
+	p := outputparser.New(outputparser.MultiValuesSetter(first, second))
+	p.HandleMessage([]byte("637beb27*53=0.9362:52=0.7792\n"))
+
+	for _, m := range []*mocks.ValuesSetter{first, second} {
+		m.AssertExpectations(t)
+		m.AssertNotCalled(t, "SetVerticalVelocity", mock.Anything)
+	}
+}
+
+func TestOutputParser_Stats(t *testing.T) {
+	p := outputparser.New(emptyValuesSetter{})
+
+	stats := p.Stats()
+	require.Zero(t, stats.Messages)
+	require.Len(t, stats.Channels, 17)
+
+	for _, msg := range []string{
+		"637beb27*53=0.9362:52=0.7792\n",
+		"637beb28*52=abc:1000=1.0\n",
+		"garbage\n",
+		"637beb28*x=1\n",
+		"",
+	} {
+		p.HandleMessage([]byte(msg))
+	}
+
+	stats = p.Stats()
+	require.Equal(t, uint64(5), stats.Messages)
+	require.Equal(t, uint64(3), stats.ParseErrors)
+	require.Equal(t, uint64(1), stats.UnknownArgs)
+	require.Equal(t, uint64(1000), stats.LastUnknownArg)
+	require.Equal(t, uint64(0x637beb28), stats.SimID)
+
+	updates := make(map[uint64]uint64)
+	for _, ch := range stats.Channels {
+		updates[ch.Arg] = ch.Updates
+		require.NotEmpty(t, ch.Name)
+		require.Equal(t, ch.Arg > 10000, ch.FlightData, ch.Name)
+	}
+
+	require.Equal(t, map[uint64]uint64{
+		24: 0, 52: 1, 53: 1,
+		10001: 0, 10002: 0, 10003: 0, 10004: 0, 10005: 0, 10006: 0, 10007: 0, 10008: 0, 10009: 0, 10010: 0, 10011: 0,
+		10012: 0, 10013: 0, 10014: 0,
+	}, updates)
+}
+
+func TestOutputParser_Sequence(t *testing.T) {
+	testObj := &mocks.ValuesSetter{}
+	testObj.On("SetRotorRPM", mock.AnythingOfType("float64"))
+
+	p := outputparser.New(testObj)
+
+	for _, msg := range []string{
+		"637beb27-1*52=0.1\n",
+		"637beb27-2*52=0.2\n",
+		"637beb27-2*52=0.3\n", // duplicate
+		"637beb27-5*52=0.4\n", // 2 packets are lost
+		"637beb27-4*52=0.5\n", // out of order
+		"637beb27*52=0.6\n",   // no sequence number
+	} {
+		p.HandleMessage([]byte(msg))
+	}
+
+	testObj.AssertNumberOfCalls(t, "SetRotorRPM", 4)
+	testObj.AssertNotCalled(t, "SetRotorRPM", 0.3*110)
+	testObj.AssertNotCalled(t, "SetRotorRPM", 0.5*110)
+
+	stats := p.Stats().Sequence
+	require.Equal(t, uint64(1), stats.Duplicates)
+	require.Equal(t, uint64(1), stats.OutOfOrder)
+	require.Equal(t, uint64(1), stats.Gaps)
+	require.Equal(t, uint64(2), stats.LostPackets)
+	require.False(t, stats.LastGapTime.IsZero())
+}
+
+func TestOutputParser_MalformedMessagesLog(t *testing.T) {
+	var buf bytes.Buffer
+
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	defer slog.SetDefault(defaultLogger)
+
+	p := outputparser.New(emptyValuesSetter{})
+
+	for i := 0; i < 100; i++ {
+		p.HandleMessage([]byte("637beb27*52=abc\n"))
+	}
+
+	require.Equal(t, uint64(100), p.Stats().ParseErrors)
+
+	// the log messages are rate-limited
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 10)
+	require.Contains(t, lines[0], `level=WARN msg="malformed message" message="637beb27*52=abc\n"`)
+	require.Contains(t, lines[0], "parsing value of argument 52")
+}
+
 func BenchmarkOutputParser_HandleMessage(b *testing.B) {
 	vs := emptyValuesSetter{}
 	p := outputparser.New(vs)
@@ -0,0 +1,56 @@
+package outputparser
+
+import "time"
+
+// maxReorderDistance is the maximal distance back the out-of-order packets are expected within, the packet
+// further back means that the exporter has been restarted, so the tracker starts over.
+const maxReorderDistance = 1000
+
+// SequenceStats holds the counters of the packet sequence numbers.
+type SequenceStats struct {
+	// Duplicates and OutOfOrder count the dropped packets that have been received more than once or too late.
+	Duplicates uint64
+	OutOfOrder uint64
+	// Gaps counts the breaks in the sequence, LostPackets counts the packets missing in them.
+	Gaps        uint64
+	LostPackets uint64
+	// LastGapTime is the time the last gap was detected, it is zero if there was none.
+	LastGapTime time.Time
+}
+
+// sequenceTracker detects lost, duplicate and out-of-order packets by the sequence numbers, it is not thread-safe.
+type sequenceTracker struct {
+	started bool
+	simID   uint64
+	lastSeq uint32
+}
+
+// check reports whether the packet must be handled and counts the sequence breaks in stats.
+func (t *sequenceTracker) check(simID uint64, seq uint32, now time.Time, stats *SequenceStats) bool {
+	if !t.started || simID != t.simID {
+		t.started, t.simID, t.lastSeq = true, simID, seq
+		return true
+	}
+
+	// the difference is calculated modulo 2^32, so that the sequence can wrap around
+	diff := seq - t.lastSeq
+
+	switch {
+	case diff == 0:
+		stats.Duplicates++
+		return false
+
+	case diff > 1<<31 && -diff <= maxReorderDistance:
+		stats.OutOfOrder++
+		return false
+
+	case diff > 1 && diff <= 1<<31:
+		stats.Gaps++
+		stats.LostPackets += uint64(diff - 1)
+		stats.LastGapTime = now
+	}
+
+	t.lastSeq = seq
+
+	return true
+}
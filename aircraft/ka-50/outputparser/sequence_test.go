@@ -0,0 +1,85 @@
+package outputparser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_sequenceTracker_check(t *testing.T) {
+	type packet struct {
+		simID uint64
+		seq   uint32
+	}
+
+	tests := []struct {
+		name       string
+		packets    []packet
+		wantAccept []bool
+		wantStats  SequenceStats
+	}{
+		{
+			name:       "in order",
+			packets:    []packet{{1, 5}, {1, 6}, {1, 7}},
+			wantAccept: []bool{true, true, true},
+		},
+		{
+			name:       "gap",
+			packets:    []packet{{1, 5}, {1, 6}, {1, 9}, {1, 10}, {1, 12}},
+			wantAccept: []bool{true, true, true, true, true},
+			wantStats:  SequenceStats{Gaps: 2, LostPackets: 3},
+		},
+		{
+			name:       "duplicate",
+			packets:    []packet{{1, 5}, {1, 5}, {1, 6}},
+			wantAccept: []bool{true, false, true},
+			wantStats:  SequenceStats{Duplicates: 1},
+		},
+		{
+			name:       "out of order",
+			packets:    []packet{{1, 5}, {1, 7}, {1, 6}, {1, 8}},
+			wantAccept: []bool{true, true, false, true},
+			wantStats:  SequenceStats{Gaps: 1, LostPackets: 1, OutOfOrder: 1},
+		},
+		{
+			name:       "wrap around",
+			packets:    []packet{{1, 0xFFFFFFFE}, {1, 0xFFFFFFFF}, {1, 0}, {1, 1}},
+			wantAccept: []bool{true, true, true, true},
+		},
+		{
+			name:       "new simulation starts over",
+			packets:    []packet{{1, 100}, {2, 0}, {2, 1}},
+			wantAccept: []bool{true, true, true},
+		},
+		{
+			name:       "exporter restart with the same simulation starts over",
+			packets:    []packet{{1, 5000}, {1, 0}, {1, 1}},
+			wantAccept: []bool{true, true, true},
+		},
+	}
+
+	now := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				tracker sequenceTracker
+				stats   SequenceStats
+				accept  []bool
+			)
+
+			for _, p := range tt.packets {
+				accept = append(accept, tracker.check(p.simID, p.seq, now, &stats))
+			}
+
+			if tt.wantStats.Gaps > 0 {
+				tt.wantStats.LastGapTime = now
+			}
+
+			require.Equal(t, tt.wantAccept, accept)
+			require.Equal(t, tt.wantStats, stats)
+		})
+	}
+}
@@ -1,21 +1,27 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"math"
 	"net"
 	"time"
+
+	"github.com/dimchansky/dcs-hmd/updlistener"
 )
 
 func main() {
-	if err := run(); err != nil {
+	address := flag.String("address", fmt.Sprintf("127.0.0.1:%d", updlistener.DefaultPort), "UDP address of the HMD to send values to")
+	flag.Parse()
+
+	if err := run(*address); err != nil {
 		log.Fatal(err)
 	}
 }
 
-func run() (err error) {
-	udpAddr, err := net.ResolveUDPAddr("udp", ":19089")
+func run(address string) (err error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", address)
 	if err != nil {
 		return err
 	}
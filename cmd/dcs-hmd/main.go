@@ -1,8 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	_ "github.com/silbinarywolf/preferdiscretegpu"
@@ -10,51 +17,481 @@ import (
 	dcshmd "github.com/dimchansky/dcs-hmd"
 	"github.com/dimchansky/dcs-hmd/aircraft/ka-50/outputparser"
 	"github.com/dimchansky/dcs-hmd/cmd"
+	"github.com/dimchansky/dcs-hmd/diagnostics"
+	"github.com/dimchansky/dcs-hmd/installer"
+	"github.com/dimchansky/dcs-hmd/metrics"
+	"github.com/dimchansky/dcs-hmd/settings"
 	"github.com/dimchansky/dcs-hmd/updlistener"
+	"github.com/dimchansky/dcs-hmd/webhmd"
 )
 
 func main() {
+	os.Exit(runCLI())
+}
+
+// runCLI runs the command given by the flags and returns the exit code.
+func runCLI() int {
 	showVersion := flag.Bool("v", false, "show version information")
-	installDir := flag.String("i", "", `install scripts to the target DCS scripts directory (usually "%USERPROFILE%\Saved Games\DCS.openbeta\Scripts")`)
+	installDir := flag.String("i", "", `install scripts to the target DCS scripts directory (usually "%USERPROFILE%\Saved Games\DCS.openbeta\Scripts"), "auto" installs to all DCS folders found in Saved Games (see -list-dcs)`)
+	listDCS := flag.Bool("list-dcs", false, "list the DCS folders found in Saved Games and exit")
 	unInstallDir := flag.String("u", "", `uninstall scripts from the target DCS scripts directory (usually "%USERPROFILE%\Saved Games\DCS.openbeta\Scripts")`)
+
+	statusDir := flag.String("status", "", "report whether the scripts installed in the target DCS scripts directory are up to date and exit")
+	rollbackDir := flag.String("rollback", "", "restore the target DCS scripts directory to the state before the last install, uninstall or fix (the backups are kept in the DCSHMD-backup folder)")
+	doctorDir := flag.String("doctor", "", "check how the installed scripts coexist with other exporters (TacView, SRS, DCS-BIOS, Helios...) in Export.lua of the target DCS scripts directory")
+	fix := flag.Bool("fix", false, "fix the issues found by -doctor")
+	scriptsFolder := flag.String("scripts-folder", dcshmd.DefaultScriptsFolder, "name of the folder in the target DCS scripts directory the scripts are installed to (used with -i, -u, -status and -doctor)")
+	dryRun := flag.Bool("dry-run", false, "print the file operations of -i or -u with the changes of Export.lua as a unified diff instead of performing them")
+
+	exportCfg := dcshmd.DefaultExportConfig()
+	exportAddress := flag.String("export-address", exportCfg.Targets[0].String(), "comma-separated list of unicast, broadcast or multicast group addresses the installed exporter sends data to (used with -i)")
+	flag.StringVar(&exportCfg.Protocol, "export-protocol", exportCfg.Protocol, `wire format of the exported data: "text" or compact "binary" (UDP transport only, used with -i)`)
+	exportAircraft := flag.String("export-aircraft", strings.Join(exportCfg.Aircraft, ","), "comma-separated list of aircraft the installed exporter exports the cockpit gauges for, supported: "+strings.Join(dcshmd.SupportedAircraft, ", ")+" (used with -i)")
+	flag.Float64Var(&exportCfg.Rates.High, "export-rate", exportCfg.Rates.High, "exports per second of the high importance data, e.g. the gauges of the HMD (used with -i)")
+	flag.Float64Var(&exportCfg.Rates.Medium, "export-medium-rate", exportCfg.Rates.Medium, "exports per second of the medium importance data, at most -export-rate (used with -i)")
+	flag.Float64Var(&exportCfg.Rates.Low, "export-low-rate", exportCfg.Rates.Low, "exports per second of the slow-changing low importance data, e.g. fuel or engine temperatures, at most -export-medium-rate (used with -i)")
+	listMonitors := flag.Bool("list-monitors", false, "list available monitors with their indexes and exit")
+
+	// window placement is remembered between runs, so the saved settings are the flag defaults
+	settingsPath, savedSettings, settingsErr := loadSettings()
+
+	runCfg := runConfig{HUD: dcshmd.DefaultHUDConfig()}
+	hudCfg := &runCfg.HUD
+	hudCfg.Window = savedSettings.Window
+	flag.StringVar(&hudCfg.Font.Path, "font", "", "path to TTF or OTF font file used to draw HUD text and gauge labels (embedded Go Regular font by default)")
+	flag.Float64Var(&hudCfg.Font.Size, "font-size", hudCfg.Font.Size, "font size in points")
+	flag.Float64Var(&hudCfg.Font.DPI, "font-dpi", hudCfg.Font.DPI, "font DPI")
+	fontHinting := flag.String("font-hinting", "full", `font hinting: "none", "vertical" or "full"`)
+	flag.IntVar(&hudCfg.Window.Monitor, "monitor", hudCfg.Window.Monitor, "index of the monitor to show the HMD on (see -list-monitors), negative value means the current monitor")
+	flag.IntVar(&hudCfg.Window.X, "window-x", hudCfg.Window.X, "window X position relative to the monitor, negative value means automatic position")
+	flag.IntVar(&hudCfg.Window.Y, "window-y", hudCfg.Window.Y, "window Y position relative to the monitor, negative value means automatic position")
+	flag.IntVar(&hudCfg.Window.Width, "window-width", hudCfg.Window.Width, fmt.Sprintf("window width of at least %d, zero means the default width", settings.MinWindowWidth))
+	flag.IntVar(&hudCfg.Window.Height, "window-height", hudCfg.Window.Height, fmt.Sprintf("window height of at least %d, zero means the default height", settings.MinWindowHeight))
+	flag.StringVar(&runCfg.Transport, "transport", transportUDP, `transport of the exported data: "udp", "tcp" or "ws"`)
+	flag.StringVar(&runCfg.Listener.Address, "listen", fmt.Sprintf(":%d", updlistener.DefaultPort), `UDP or TCP address to listen for the exported data on, use "127.0.0.1:port" to accept data from the local machine only`)
+	flag.StringVar(&runCfg.ConnectAddress, "connect", "", `TCP address or WebSocket URL to connect to for the exported data with "tcp" or "ws" transport, the connection is re-established when it breaks`)
+	flag.StringVar(&runCfg.Listener.MulticastGroup, "multicast-group", "", "IP address of the multicast group to join to receive the exported data")
+	flag.StringVar(&runCfg.Listener.MulticastInterface, "multicast-interface", "", "name of the network interface to join the multicast group on (system-assigned by default)")
+	allowPeers := flag.String("allow-peers", "", "comma-separated list of IP addresses the exported data is accepted from (any address by default)")
+	flag.BoolVar(&runCfg.Listener.RequestRefresh, "request-refresh", true, "request all the values from every new UDP sender, so that the gauges don't show the defaults until the values change")
+	flag.BoolVar(&hudCfg.Diagnostics, "diagnostics", false, "show the diagnostics overlay with the listener and parser statistics on start (RCtrl+RShift+F10 toggles it)")
+	flag.BoolVar(&hudCfg.Hover, "hover", false, "show the hover display in place of the ground velocity vector on start (RCtrl+RShift+H toggles it)")
+	flag.StringVar(&runCfg.HTTPAddress, "http", "", `serve the web HMD for a browser on the address, e.g. ":8080" (disabled by default)`)
+	flag.StringVar(&runCfg.MetricsAddress, "metrics", "", `serve Prometheus metrics on http://<address>/metrics, e.g. "127.0.0.1:9100" (disabled by default)`)
+	logFile := flag.String("log-file", "", "append log messages to the file instead of printing them to stderr")
+	logLevel := flag.String("log-level", "info", `log level: "debug", "info", "warn" or "error"`)
 	flag.Parse()
 
+	closeLog, err := setupLogging(*logFile, *logLevel)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	defer closeLog()
+
+	if settingsErr != nil {
+		slog.Warn("failed to load settings", "error", settingsErr)
+	}
+
 	if *showVersion {
 		fmt.Printf("Version: %s\nBuild Time: %s\nGit Hash: %s\n",
 			cmd.Version, cmd.BuildTime, cmd.GitHash)
-		return
+		return 0
 	}
 
 	if *installDir != "" {
-		if err := dcshmd.InstallScripts(*installDir, true); err != nil {
-			fmt.Println("error:", err)
-			return
+		targets, err := dcshmd.ParseExportTargets(*exportAddress)
+		if err != nil {
+			slog.Error("invalid export address", "error", err)
+			return 1
 		}
-		fmt.Printf("all scripts are successfully installed to the folder: %s", *installDir)
-		fmt.Println()
-		return
+		exportCfg.Targets = targets
+		exportCfg.Aircraft = dcshmd.ParseExportAircraft(*exportAircraft)
+		// the dry run must not create the missing scripts folders
+		dirs, err := installDirs(*installDir, !*dryRun)
+		if err != nil {
+			slog.Error("failed to find DCS scripts folders", "error", err)
+			return 1
+		}
+		for _, dir := range dirs {
+			if *dryRun {
+				plan, err := dcshmd.PlanInstall(dir, *scriptsFolder, &exportCfg, cmd.Version)
+				if err != nil {
+					slog.Error("failed to plan installation", "dir", dir, "error", err)
+					return 1
+				}
+				printPlan(dir, plan)
+				continue
+			}
+			if err := dcshmd.InstallScripts(dir, *scriptsFolder, &exportCfg, cmd.Version, true); err != nil {
+				slog.Error("failed to install scripts", "dir", dir, "error", err)
+				return 1
+			}
+			fmt.Printf("all scripts are successfully installed to the folder: %s", dir)
+			fmt.Println()
+		}
+		return 0
+	}
+
+	if *listDCS {
+		dirs, err := dcshmd.DiscoverDCSDirs()
+		if err != nil {
+			slog.Error("failed to find DCS folders", "error", err)
+			return 1
+		}
+		if len(dirs) == 0 {
+			fmt.Println("no DCS folders found in Saved Games")
+		}
+		for _, dir := range dirs {
+			scripts := "no scripts folder"
+			if dir.HasScripts {
+				scripts = dir.ScriptsDir
+			}
+			fmt.Printf("%s: %s (%s)\n", dir.Variant, dir.Path, scripts)
+		}
+		return 0
 	}
 
 	if *unInstallDir != "" {
-		if err := dcshmd.UninstallScripts(*unInstallDir, true); err != nil {
-			fmt.Println("error:", err)
-			return
+		if *dryRun {
+			plan, err := dcshmd.PlanUninstall(*unInstallDir, *scriptsFolder)
+			if err != nil {
+				slog.Error("failed to plan uninstallation", "error", err)
+				return 1
+			}
+			printPlan(*unInstallDir, plan)
+			return 0
+		}
+		if err := dcshmd.UninstallScripts(*unInstallDir, *scriptsFolder, true); err != nil {
+			slog.Error("failed to uninstall scripts", "error", err)
+			return 1
 		}
 		fmt.Printf("all scripts were successfully uninstalled from the folder: %s", *unInstallDir)
 		fmt.Println()
-		return
+		return 0
+	}
+
+	if *statusDir != "" {
+		status, err := dcshmd.CheckScripts(*statusDir, *scriptsFolder)
+		if err != nil {
+			slog.Error("failed to check scripts", "error", err)
+			return 1
+		}
+		printScriptsStatus(status)
+		return 0
+	}
+
+	if *rollbackDir != "" {
+		if err := dcshmd.RollbackScripts(*rollbackDir, true); err != nil {
+			slog.Error("failed to roll back scripts", "error", err)
+			return 1
+		}
+		fmt.Printf("the previous state of the folder is restored: %s", *rollbackDir)
+		fmt.Println()
+		return 0
+	}
+
+	if *doctorDir != "" {
+		report, err := dcshmd.CheckExportScript(*doctorDir, *scriptsFolder, *fix, true)
+		if err != nil {
+			slog.Error("failed to check scripts", "error", err)
+			return 1
+		}
+		fmt.Print(report)
+		if !*fix && report.Fixable() {
+			fmt.Println("run with -fix to fix the fixable issues")
+		}
+		// the errors left after the fix fail the check, so that it can be used in the scripts
+		if report.HasErrors() {
+			return 1
+		}
+		return 0
+	}
+
+	if *listMonitors {
+		for i, name := range dcshmd.Monitors() {
+			fmt.Printf("%d: %s\n", i, name)
+		}
+		return 0
+	}
+
+	hinting, err := dcshmd.ParseFontHinting(*fontHinting)
+	if err != nil {
+		slog.Error("invalid font hinting", "error", err)
+		return 1
+	}
+	hudCfg.Font.Hinting = hinting
+
+	if runCfg.Listener.AllowedPeers, err = parseIPs(*allowPeers); err != nil {
+		slog.Error("invalid allowed peers", "error", err)
+		return 1
+	}
+
+	if err := hudCfg.Window.Validate(); err != nil {
+		slog.Error("invalid window size", "error", err)
+		return 1
+	}
+
+	if settingsPath != "" && savedSettings.Window != hudCfg.Window {
+		savedSettings.Window = hudCfg.Window
+		if err := settings.Save(settingsPath, &savedSettings); err != nil {
+			slog.Warn("failed to save settings", "error", err)
+		}
+	}
+
+	// the saved monitor may have been unplugged since, it is kept in the settings for the next runs
+	if !isFlagSet("monitor") && hudCfg.Window.Monitor >= len(dcshmd.Monitors()) {
+		slog.Warn("saved monitor is not found, the current monitor is used", "monitor", hudCfg.Window.Monitor)
+		hudCfg.Window.Monitor = -1
+	}
+
+	slog.Info("starting HMD", "version", cmd.Version, "gitHash", cmd.GitHash, "transport", runCfg.Transport)
+
+	if err := run(&runCfg); err != nil {
+		slog.Error("HMD has stopped with error", "error", err)
+		return 1
+	}
+
+	slog.Info("HMD has stopped")
+
+	return 0
+}
+
+// loadSettings loads the settings saved by the previous run, it returns empty path if the settings can't be saved.
+// The settings are defaults if the error is returned.
+func loadSettings() (string, settings.Settings, error) {
+	path, err := settings.DefaultPath()
+	if err != nil {
+		return "", settings.Default(), err
+	}
+
+	s, err := settings.Load(path)
+
+	return path, s, err
+}
+
+// setupLogging sets the default logger for the level, the log is appended to the file if the path is not empty.
+// The errors are printed to stderr in that case too. The returned function closes the log file.
+func setupLogging(path, level string) (func(), error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level '%s': %w", level, err)
+	}
+
+	var (
+		w        io.Writer = os.Stderr
+		closeLog           = func() {}
+	)
+
+	if path != "" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log file '%s': %w", path, err)
+		}
+
+		w = f
+		closeLog = func() { _ = f.Close() }
+	}
+
+	var h slog.Handler = slog.NewTextHandler(w, &slog.HandlerOptions{Level: lvl})
+	if path != "" {
+		// the user must see why the command has failed on the console too
+		h = &errorTeeHandler{Handler: h, console: slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError})}
+	}
+
+	slog.SetDefault(slog.New(h))
+
+	return closeLog, nil
+}
+
+// errorTeeHandler passes the log records to the handler and the errors also to the console handler.
+type errorTeeHandler struct {
+	slog.Handler
+	console slog.Handler
+}
+
+func (h *errorTeeHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelError {
+		_ = h.console.Handle(ctx, r.Clone())
+	}
 
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *errorTeeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &errorTeeHandler{Handler: h.Handler.WithAttrs(attrs), console: h.console.WithAttrs(attrs)}
+}
+
+func (h *errorTeeHandler) WithGroup(name string) slog.Handler {
+	return &errorTeeHandler{Handler: h.Handler.WithGroup(name), console: h.console.WithGroup(name)}
+}
+
+// isFlagSet reports whether the flag has been passed on the command line.
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+
+	return set
+}
+
+// printScriptsStatus prints the status of the installed scripts compared to the ones of this version.
+func printScriptsStatus(status *dcshmd.ScriptsStatus) {
+	if !status.Installed {
+		fmt.Println("installed: no")
+	} else {
+		version := status.Version
+		if version == "" {
+			version = "unknown"
+		}
+		fmt.Printf("installed: yes, version %s (this is %s)\n", version, cmd.Version)
+	}
+
+	hook := "missing"
+	if status.HookPresent {
+		hook = "present"
+	}
+	fmt.Printf("Export.lua hook: %s\n", hook)
+
+	fmt.Println("files:")
+	for _, f := range status.Files {
+		fmt.Printf("  %s: %s\n", f.Path, f.Status)
+	}
+
+	if status.UpToDate(cmd.Version) {
+		fmt.Println("the scripts are up to date")
+	} else {
+		fmt.Println("the scripts are not up to date, install them with -i")
+	}
+}
+
+// printPlan prints the planned file operations in the directory, nothing is changed. The missing directory
+// is reported as the one to be created.
+func printPlan(dir string, plan *installer.Plan) {
+	fmt.Printf("dry run in the folder: %s\n", dir)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		fmt.Printf("create folder %s\n", dir)
+	}
+	fmt.Print(plan)
+}
+
+// autoInstallDir is the value of -i flag that selects all the DCS folders found in Saved Games.
+const autoInstallDir = "auto"
+
+// installDirs returns the scripts directories to install the scripts to. If dir is autoInstallDir, these are
+// the scripts directories of all the DCS folders found in Saved Games, the missing ones are created if create
+// is true.
+func installDirs(dir string, create bool) ([]string, error) {
+	if dir != autoInstallDir {
+		return []string{dir}, nil
+	}
+
+	found, err := dcshmd.DiscoverDCSDirs()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(found) == 0 {
+		return nil, errors.New("no DCS folders found in Saved Games, specify the scripts folder instead of auto")
 	}
 
-	if err := run(); err != nil {
-		fmt.Println("error:", err)
+	dirs := make([]string, 0, len(found))
+
+	for _, d := range found {
+		if !d.HasScripts && create {
+			if err := os.Mkdir(d.ScriptsDir, 0755); err != nil {
+				return nil, fmt.Errorf("failed to create scripts folder '%s': %w", d.ScriptsDir, err)
+			}
+		}
+
+		dirs = append(dirs, d.ScriptsDir)
+	}
+
+	return dirs, nil
+}
+
+// runConfig holds the options of the HMD.
+type runConfig struct {
+	HUD dcshmd.HUDConfig
+	// Transport is one of transportUDP, transportTCP or transportWebSocket.
+	Transport string
+	Listener  updlistener.Config
+	// ConnectAddress is the TCP address or WebSocket URL to connect to, with TCP transport the listener
+	// accepts connections on the Listener.Address if it is empty.
+	ConnectAddress string
+	// HTTPAddress is the address of the web HMD server, the server is disabled if it is empty.
+	HTTPAddress string
+	// MetricsAddress is the address of the metrics server, the server is disabled if it is empty.
+	MetricsAddress string
+}
+
+const (
+	transportUDP       = "udp"
+	transportTCP       = "tcp"
+	transportWebSocket = "ws"
+)
+
+// startListener starts the listener of the exported data for the configured transport.
+func startListener(cfg *runConfig, msgHandler updlistener.MessageHandler) (updlistener.Listener, error) {
+	switch cfg.Transport {
+	case transportUDP:
+		l, err := updlistener.New(&cfg.Listener, msgHandler)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create UDP listener: %w", err)
+		}
+
+		return l, nil
+
+	case transportTCP:
+		if cfg.ConnectAddress != "" {
+			return updlistener.DialTCP(cfg.ConnectAddress, msgHandler), nil
+		}
+
+		l, err := updlistener.ListenTCP(cfg.Listener.Address, msgHandler)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create TCP listener: %w", err)
+		}
+
+		return l, nil
+
+	case transportWebSocket:
+		if cfg.ConnectAddress == "" {
+			return nil, errors.New("WebSocket URL to connect to is not set")
+		}
+
+		return updlistener.DialWebSocket(cfg.ConnectAddress, msgHandler), nil
+
+	default:
+		return nil, fmt.Errorf("unknown transport: '%s'", cfg.Transport)
 	}
 }
 
-const udpPortToListen = 19089
+// parseIPs parses the comma-separated list of IP addresses.
+func parseIPs(s string) ([]net.IP, error) {
+	var ips []net.IP
+
+	for _, str := range strings.Split(s, ",") {
+		str = strings.TrimSpace(str)
+		if str == "" {
+			continue
+		}
+
+		ip := net.ParseIP(str)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address: '%s'", str)
+		}
+
+		ips = append(ips, ip)
+	}
+
+	return ips, nil
+}
 
-func run() error {
-	hud, err := dcshmd.NewHUD()
+func run(cfg *runConfig) error {
+	hud, err := dcshmd.NewHUD(&cfg.HUD)
 	if err != nil {
 		return fmt.Errorf("failed to create HUD: %w", err)
 	}
@@ -63,15 +500,63 @@ func run() error {
 		_ = hud.Close()
 	}()
 
-	l, err := updlistener.New(udpPortToListen, outputparser.New(hud))
+	valuesSetters := []outputparser.ValuesSetter{hud}
+
+	if cfg.HTTPAddress != "" {
+		web, err := webhmd.New(cfg.HTTPAddress)
+		if err != nil {
+			return fmt.Errorf("failed to start web HMD server: %w", err)
+		}
+
+		defer func() {
+			_ = web.Close()
+		}()
+
+		slog.Info("web HMD is available", "url", fmt.Sprintf("http://%s/", web.Addr()))
+
+		valuesSetters = append(valuesSetters, web)
+	}
+
+	var values *metrics.Values
+	if cfg.MetricsAddress != "" {
+		values = metrics.NewValues()
+		valuesSetters = append(valuesSetters, values)
+	}
+
+	parser := outputparser.New(outputparser.MultiValuesSetter(valuesSetters...))
+
+	l, err := startListener(cfg, parser)
 	if err != nil {
-		return fmt.Errorf("failed to create UDP listener: %w", err)
+		return err
 	}
 
 	defer func() {
 		_ = l.Close()
 	}()
 
+	hud.SetDiagnostics(diagnostics.New(l, parser))
+
+	if cfg.MetricsAddress != "" {
+		hudMetrics := dcshmd.NewHUDMetrics()
+		hud.SetMetrics(hudMetrics)
+
+		srv, err := metrics.New(cfg.MetricsAddress,
+			metrics.ListenerCollector(l),
+			metrics.ParserCollector(parser),
+			values,
+			hudMetrics,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to start metrics server: %w", err)
+		}
+
+		defer func() {
+			_ = srv.Close()
+		}()
+
+		slog.Info("metrics are available", "url", fmt.Sprintf("http://%s%s", srv.Addr(), metrics.Path))
+	}
+
 	if err := ebiten.RunGame(hud); err != nil {
 		return fmt.Errorf("failed to run HUD: %w", err)
 	}
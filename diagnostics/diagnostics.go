@@ -0,0 +1,196 @@
+// Package diagnostics turns the listener and parser counters into the human-readable lines of the debug overlay.
+package diagnostics
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dimchansky/dcs-hmd/aircraft/ka-50/outputparser"
+	"github.com/dimchansky/dcs-hmd/updlistener"
+)
+
+const (
+	// rateInterval is the minimal interval the rates are averaged over.
+	rateInterval = time.Second
+
+	// linkDegradedPeriod is how long the link is considered degraded after the packet loss.
+	linkDegradedPeriod = 5 * time.Second
+)
+
+// ListenerStats is a source of the listener counters, updlistener.Listener implements it.
+type ListenerStats interface {
+	Stats() updlistener.Stats
+}
+
+// ParserStats is a source of the parser counters, *outputparser.OutputParser implements it.
+type ParserStats interface {
+	Stats() outputparser.Stats
+}
+
+// New creates the diagnostics of the listener and the parser.
+func New(listener ListenerStats, parser ParserStats) *Diagnostics {
+	return &Diagnostics{listener: listener, parser: parser}
+}
+
+// Diagnostics samples the counters and calculates the rates, it is not thread-safe.
+type Diagnostics struct {
+	listener ListenerStats
+	parser   ParserStats
+
+	// counters at the beginning of the current rate interval
+	sampled         bool
+	sampleTime      time.Time
+	sampleListener  updlistener.Stats
+	sampleUpdates   []uint64
+	packetsRate     float64
+	bytesRate       float64
+	channelsUpdates []float64
+}
+
+// Lines returns the diagnostics lines for the moment.
+func (d *Diagnostics) Lines(now time.Time) []string {
+	ls := d.listener.Stats()
+	ps := d.parser.Stats()
+
+	d.updateRates(now, &ls, &ps)
+
+	lines := []string{
+		fmt.Sprintf("listener: %.1f packets/s, %.1f KiB/s, %d truncated, %d dropped",
+			d.packetsRate, d.bytesRate/1024, ls.Truncated, ls.Dropped),
+		"last packet: " + lastPacket(now, &ls),
+		fmt.Sprintf("parser: %d parse errors, %d unknown args%s", ps.ParseErrors, ps.UnknownArgs, lastUnknownArg(&ps)),
+		"sim ID: " + simID(&ps),
+		fmt.Sprintf("link: %d lost in %d gaps, %d duplicates, %d out of order",
+			ps.Sequence.LostPackets, ps.Sequence.Gaps, ps.Sequence.Duplicates, ps.Sequence.OutOfOrder),
+	}
+
+	for i, ch := range ps.Channels {
+		if !ch.FlightData {
+			lines = append(lines, fmt.Sprintf("%d %s: %.1f/s, %s", ch.Arg, ch.Name, d.channelsUpdates[i], since(now, ch.LastUpdate)))
+		}
+	}
+
+	// the flight data channels are sent together, so they are summarized on one line to keep the overlay short
+	if line, ok := d.flightDataLine(now, ps.Channels); ok {
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// flightDataLine returns the line with the slowest rate and the oldest update of the flight data channels,
+// ok is false if there are none.
+func (d *Diagnostics) flightDataLine(now time.Time, channels []outputparser.ChannelStats) (line string, ok bool) {
+	var (
+		count   int
+		minRate float64
+		oldest  time.Time
+		never   bool
+	)
+
+	for i, ch := range channels {
+		if !ch.FlightData {
+			continue
+		}
+
+		if count == 0 || d.channelsUpdates[i] < minRate {
+			minRate = d.channelsUpdates[i]
+		}
+
+		switch {
+		case ch.LastUpdate.IsZero():
+			never = true
+		case oldest.IsZero() || ch.LastUpdate.Before(oldest):
+			oldest = ch.LastUpdate
+		}
+
+		count++
+	}
+
+	if count == 0 {
+		return "", false
+	}
+
+	if never {
+		oldest = time.Time{}
+	}
+
+	return fmt.Sprintf("flight data: %d channels, %.1f/s min, %s", count, minRate, since(now, oldest)), true
+}
+
+// LinkDegraded reports whether packets have been lost recently, so the indicators may show stale values.
+func (d *Diagnostics) LinkDegraded(now time.Time) bool {
+	lastGap := d.parser.Stats().Sequence.LastGapTime
+
+	return !lastGap.IsZero() && now.Sub(lastGap) < linkDegradedPeriod
+}
+
+// updateRates recalculates the rates once per rateInterval.
+func (d *Diagnostics) updateRates(now time.Time, ls *updlistener.Stats, ps *outputparser.Stats) {
+	if len(d.channelsUpdates) != len(ps.Channels) {
+		d.channelsUpdates = make([]float64, len(ps.Channels))
+		d.sampled = false
+	}
+
+	if d.sampled {
+		elapsed := now.Sub(d.sampleTime)
+		if elapsed < rateInterval {
+			return
+		}
+
+		seconds := elapsed.Seconds()
+		d.packetsRate = float64(ls.Packets-d.sampleListener.Packets) / seconds
+		d.bytesRate = float64(ls.Bytes-d.sampleListener.Bytes) / seconds
+
+		for i, ch := range ps.Channels {
+			d.channelsUpdates[i] = float64(ch.Updates-d.sampleUpdates[i]) / seconds
+		}
+	}
+
+	d.sampled = true
+	d.sampleTime = now
+	d.sampleListener = *ls
+	d.sampleUpdates = d.sampleUpdates[:0]
+
+	for _, ch := range ps.Channels {
+		d.sampleUpdates = append(d.sampleUpdates, ch.Updates)
+	}
+}
+
+func lastPacket(now time.Time, ls *updlistener.Stats) string {
+	if ls.LastSender == nil {
+		return since(now, ls.LastPacketTime)
+	}
+
+	return fmt.Sprintf("%s from %s", since(now, ls.LastPacketTime), ls.LastSender)
+}
+
+func lastUnknownArg(ps *outputparser.Stats) string {
+	if ps.UnknownArgs == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(" (last %d)", ps.LastUnknownArg)
+}
+
+func simID(ps *outputparser.Stats) string {
+	if ps.SimID == 0 {
+		return "none"
+	}
+
+	return fmt.Sprintf("%08x", ps.SimID)
+}
+
+// since formats the time passed since t, the precision is reduced to keep the lines stable between frames.
+func since(now, t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+
+	d := now.Sub(t)
+	if d < 0 {
+		d = 0
+	}
+
+	return fmt.Sprintf("%.1fs ago", d.Seconds())
+}
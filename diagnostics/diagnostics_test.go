@@ -0,0 +1,91 @@
+package diagnostics
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimchansky/dcs-hmd/aircraft/ka-50/outputparser"
+	"github.com/dimchansky/dcs-hmd/updlistener"
+)
+
+func TestDiagnostics_Lines(t *testing.T) {
+	start := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	var (
+		ls updlistener.Stats
+		ps = outputparser.Stats{Channels: []outputparser.ChannelStats{
+			{Arg: 52, Name: "rotor RPM"},
+			{Arg: 10001, Name: "indicated airspeed", FlightData: true},
+			{Arg: 10002, Name: "altitude AGL", FlightData: true},
+		}}
+	)
+
+	d := New(listenerStatsFunc(func() updlistener.Stats { return ls }), parserStatsFunc(func() outputparser.Stats { return ps }))
+
+	require.Equal(t, []string{
+		"listener: 0.0 packets/s, 0.0 KiB/s, 0 truncated, 0 dropped",
+		"last packet: never",
+		"parser: 0 parse errors, 0 unknown args",
+		"sim ID: none",
+		"link: 0 lost in 0 gaps, 0 duplicates, 0 out of order",
+		"52 rotor RPM: 0.0/s, never",
+		"flight data: 2 channels, 0.0/s min, never",
+	}, d.Lines(start))
+
+	ls = updlistener.Stats{
+		Packets:        60,
+		Bytes:          2048,
+		Truncated:      1,
+		Dropped:        2,
+		LastSender:     &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5555},
+		LastPacketTime: start.Add(1500 * time.Millisecond),
+	}
+	ps.ParseErrors = 3
+	ps.UnknownArgs = 4
+	ps.LastUnknownArg = 1000
+	ps.SimID = 0x637beb27
+	ps.Channels[0].Updates = 30
+	ps.Channels[0].LastUpdate = start.Add(time.Second)
+	ps.Channels[1].Updates = 40
+	ps.Channels[1].LastUpdate = start.Add(time.Second)
+	ps.Channels[2].Updates = 20
+	ps.Channels[2].LastUpdate = start.Add(1500 * time.Millisecond)
+	ps.Sequence = outputparser.SequenceStats{Gaps: 2, LostPackets: 3, Duplicates: 1, LastGapTime: start.Add(time.Second)}
+
+	// the rates are not recalculated until the rate interval has passed
+	require.Equal(t, "listener: 0.0 packets/s, 0.0 KiB/s, 1 truncated, 2 dropped", d.Lines(start.Add(time.Second / 2))[0])
+
+	require.Equal(t, []string{
+		"listener: 30.0 packets/s, 1.0 KiB/s, 1 truncated, 2 dropped",
+		"last packet: 0.5s ago from 127.0.0.1:5555",
+		"parser: 3 parse errors, 4 unknown args (last 1000)",
+		"sim ID: 637beb27",
+		"link: 3 lost in 2 gaps, 1 duplicates, 0 out of order",
+		"52 rotor RPM: 15.0/s, 1.0s ago",
+		"flight data: 2 channels, 10.0/s min, 1.0s ago",
+	}, d.Lines(start.Add(2*time.Second)))
+}
+
+func TestDiagnostics_LinkDegraded(t *testing.T) {
+	start := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	var ps outputparser.Stats
+
+	d := New(nil, parserStatsFunc(func() outputparser.Stats { return ps }))
+	require.False(t, d.LinkDegraded(start))
+
+	ps.Sequence.LastGapTime = start
+	require.True(t, d.LinkDegraded(start.Add(time.Second)))
+	require.False(t, d.LinkDegraded(start.Add(linkDegradedPeriod)))
+}
+
+type listenerStatsFunc func() updlistener.Stats
+
+func (f listenerStatsFunc) Stats() updlistener.Stats { return f() }
+
+type parserStatsFunc func() outputparser.Stats
+
+func (f parserStatsFunc) Stats() outputparser.Stats { return f() }
@@ -0,0 +1,19 @@
+package dcshmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dimchansky/dcs-hmd/installer"
+)
+
+// DiscoverDCSDirs finds the DCS write directories in the Saved Games folder of the current user,
+// including the Saved Games folder redirected to another location.
+func DiscoverDCSDirs() ([]installer.DCSDir, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate home directory: %w", err)
+	}
+
+	return installer.Discover(home, installer.SystemKnownFolders())
+}
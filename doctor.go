@@ -0,0 +1,68 @@
+package dcshmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/dimchansky/dcs-hmd/installer"
+)
+
+// exportHook returns the line the installer adds to Export.lua to load the scripts from the folder,
+// the script path is relative to the DCS write directory.
+func exportHook(folder string) *installer.Hook {
+	return &installer.Hook{
+		Line:   exportLuaLine(folder),
+		Script: path.Join("Scripts", folder, exportLuaFileName),
+	}
+}
+
+// CheckExportScript checks how the scripts installed in the folder coexist with the other exporters in Export.lua
+// of the specified scripts directory. If fix is true, the fixable issues are fixed and the fixed script is checked
+// again. If verbose is true, the function prints detailed log messages to stdout.
+func CheckExportScript(scriptsInstallDir, folder string, fix, verbose bool) (*installer.Report, error) {
+	if err := validateScriptsFolder(folder); err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(scriptsInstallDir); os.IsNotExist(err) {
+		return nil, fmt.Errorf("folder does not exist: '%s'", scriptsInstallDir)
+	}
+
+	// the scripts are loaded relative to the DCS write directory, which is the parent of the scripts directory
+	writeDir := os.DirFS(filepath.Dir(scriptsInstallDir))
+	exportPath := path.Join(filepath.Base(scriptsInstallDir), exportLuaFileName)
+	exportFile := filepath.Join(scriptsInstallDir, exportLuaFileName)
+
+	hook := exportHook(folder)
+
+	logStep(verbose, "checking script file", exportFile)
+
+	report, err := installer.Diagnose(writeDir, exportPath, hook)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check '%s' script: %w", exportFile, err)
+	}
+
+	if !fix || !report.Fixable() {
+		return report, nil
+	}
+
+	slog.Info("fixing script", "path", exportFile)
+	logStep(verbose, "fixing script file", exportFile)
+
+	err = inTransaction(scriptsInstallDir, verbose, func(tx *installer.Transaction) error {
+		return tx.WriteFile(exportLuaFileName, installer.Fix(report, hook))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fix '%s' script: %w", exportFile, err)
+	}
+
+	report, err = installer.Diagnose(writeDir, exportPath, hook)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check '%s' script: %w", exportFile, err)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,223 @@
+package dcshmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dimchansky/dcs-hmd/updlistener"
+)
+
+// ExportConfig holds the options of the installed exporter, they are written into the generated Config.lua.
+type ExportConfig struct {
+	// Targets are the addresses the exporter sends data to. Each of them can be a unicast, broadcast
+	// or multicast group address, so that several HMDs on the LAN can receive the same data.
+	Targets []ExportTarget
+	// Protocol is the wire format of the exported data: ProtocolText or ProtocolBinary.
+	Protocol string
+	// Rates are the export rates of the argument importance tiers.
+	Rates ExportRates
+	// Aircraft are the names of the aircraft the cockpit arguments are exported for, see SupportedAircraft.
+	// The flight data is exported for every airframe.
+	Aircraft []string
+}
+
+// SupportedAircraft are the names of the aircraft the exporter has the arguments for.
+var SupportedAircraft = []string{"Ka-50"}
+
+// ExportRates holds the export rates of the argument importance tiers in exports per second.
+// The high importance arguments are exported on every export event, so High is the rate of the export events.
+// Slow-changing values, e.g. fuel or engine temperatures, are exported at the Medium or Low rate,
+// so that they don't cost export time in every frame.
+type ExportRates struct {
+	High   float64
+	Medium float64
+	Low    float64
+}
+
+// maxExportRate is the maximal export rate, DCS doesn't call the exporter more often than once a frame anyway.
+const maxExportRate = 1000
+
+// Wire formats of the exported data.
+const (
+	// ProtocolText is the human-readable text format, it is supported by all transports.
+	ProtocolText = "text"
+	// ProtocolBinary is the compact binary format with sequence numbers and checksums, it is supported by UDP only.
+	ProtocolBinary = "binary"
+)
+
+// ExportTarget is an address the exporter sends data to.
+type ExportTarget struct {
+	Host string
+	Port int
+}
+
+func (t ExportTarget) String() string {
+	return net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+}
+
+// DefaultExportConfig returns the exporter options used by default.
+func DefaultExportConfig() ExportConfig {
+	return ExportConfig{
+		Targets: []ExportTarget{
+			{Host: "127.0.0.1", Port: updlistener.DefaultPort},
+		},
+		Protocol: ProtocolText,
+		Rates: ExportRates{
+			High:   100,
+			Medium: 20,
+			Low:    2,
+		},
+		Aircraft: slices.Clone(SupportedAircraft),
+	}
+}
+
+// ParseExportTargets parses the comma-separated list of addresses in "host:port" form.
+func ParseExportTargets(addresses string) ([]ExportTarget, error) {
+	var targets []ExportTarget
+
+	for _, address := range strings.Split(addresses, ",") {
+		address = strings.TrimSpace(address)
+		if address == "" {
+			continue
+		}
+
+		host, portStr, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid export address '%s': %w", address, err)
+		}
+
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port in export address '%s': %w", address, err)
+		}
+
+		target := ExportTarget{Host: host, Port: port}
+		if err := target.validate(); err != nil {
+			return nil, err
+		}
+
+		targets = append(targets, target)
+	}
+
+	return targets, nil
+}
+
+// ParseExportAircraft parses the comma-separated list of aircraft names.
+func ParseExportAircraft(names string) []string {
+	var aircraft []string
+
+	for _, name := range strings.Split(names, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			aircraft = append(aircraft, name)
+		}
+	}
+
+	return aircraft
+}
+
+func (c *ExportConfig) validate() error {
+	if len(c.Targets) == 0 {
+		return errors.New("no export addresses")
+	}
+
+	for _, t := range c.Targets {
+		if err := t.validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.Protocol != ProtocolText && c.Protocol != ProtocolBinary {
+		return fmt.Errorf("unknown export protocol: '%s'", c.Protocol)
+	}
+
+	if len(c.Aircraft) == 0 {
+		return errors.New("no aircraft to export data for")
+	}
+
+	for _, a := range c.Aircraft {
+		if !slices.Contains(SupportedAircraft, a) {
+			return fmt.Errorf("unsupported aircraft '%s', supported ones: %s", a, strings.Join(SupportedAircraft, ", "))
+		}
+	}
+
+	return c.Rates.validate()
+}
+
+func (r *ExportRates) validate() error {
+	rates := []struct {
+		name string
+		rate float64
+		max  float64
+	}{
+		{"high", r.High, maxExportRate},
+		{"medium", r.Medium, r.High},
+		{"low", r.Low, r.Medium},
+	}
+
+	for _, t := range rates {
+		// NaN fails the comparisons too
+		if !(t.rate > 0 && t.rate <= t.max) {
+			return fmt.Errorf("invalid %s importance export rate %g, it must be greater than 0 and at most %g",
+				t.name, t.rate, t.max)
+		}
+	}
+
+	return nil
+}
+
+func (t *ExportTarget) validate() error {
+	if t.Host == "" {
+		return errors.New("export host is empty")
+	}
+
+	// the host is written into lua string literal, so only the characters of host names and IP addresses are allowed
+	for _, r := range t.Host {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune(".-_:", r)) {
+			return fmt.Errorf("invalid character %q in export host '%s'", r, t.Host)
+		}
+	}
+
+	if t.Port <= 0 || t.Port > 65535 {
+		return fmt.Errorf("invalid export port: %d", t.Port)
+	}
+
+	return nil
+}
+
+// luaConfig returns the contents of Config.lua file.
+func (c *ExportConfig) luaConfig() []byte {
+	var b bytes.Buffer
+
+	fmt.Fprintln(&b, "-- Generated by dcs-hmd installer, the changes are overwritten on the next install.")
+	fmt.Fprintln(&b, "DCSHMD_Config = {")
+	fmt.Fprintln(&b, "    Targets = {")
+
+	for _, t := range c.Targets {
+		fmt.Fprintf(&b, "        { Host = \"%s\", Port = %d },\n", t.Host, t.Port)
+	}
+
+	fmt.Fprintln(&b, "    },")
+	fmt.Fprintf(&b, "    Protocol = \"%s\",\n", c.Protocol)
+	fmt.Fprintf(&b, "    Rates = { High = %s, Medium = %s, Low = %s },\n",
+		luaNumber(c.Rates.High), luaNumber(c.Rates.Medium), luaNumber(c.Rates.Low))
+	fmt.Fprintln(&b, "    Aircraft = {")
+
+	for _, a := range c.Aircraft {
+		fmt.Fprintf(&b, "        \"%s\",\n", a)
+	}
+
+	fmt.Fprintln(&b, "    },")
+	fmt.Fprintln(&b, "}")
+
+	return b.Bytes()
+}
+
+// luaNumber formats the finite number as lua number literal.
+func luaNumber(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,484 @@
+// Package exportertest runs the exporter scripts in the embedded Lua interpreter with the stubbed DCS API,
+// so that the scripts can be tested end to end without DCS.
+package exportertest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+
+	"github.com/dimchansky/dcs-hmd/aircraft/ka-50/outputparser"
+)
+
+// WriteDirPath is the path lfs.writedir() returns, the paths the scripts open under it are mapped to the write
+// directory filesystem of the harness.
+const WriteDirPath = `C:\Users\Pilot\Saved Games\DCS\`
+
+// Log levels of the DCS log module.
+const (
+	LogAlert   = 1
+	LogError   = 2
+	LogWarning = 4
+	LogInfo    = 8
+	LogDebug   = 16
+	LogTrace   = 32
+)
+
+// LogMessage is the message the scripts write with log.write.
+type LogMessage struct {
+	Subsystem string
+	Level     int
+	Message   string
+}
+
+func (m LogMessage) String() string {
+	return m.Subsystem + ": " + m.Message
+}
+
+// Datagram is the datagram the scripts send with the UDP socket.
+type Datagram struct {
+	Data []byte
+	Host string
+	Port int
+}
+
+// Harness is the Lua interpreter with the DCS API the exporter uses: lfs, log, io.open, LoGetSelfData,
+// GetDevice, the LoGet* functions of the flight data and the LuaSocket UDP socket that captures the sent datagrams.
+// It is not thread-safe.
+type Harness struct {
+	// Aircraft is the name of the aircraft LoGetSelfData returns, LoGetSelfData returns nil if it is empty.
+	Aircraft string
+	// Arguments are the cockpit argument values the main panel device returns.
+	Arguments map[int]float64
+	// FlightData holds the values the LoGet* functions and the Heading and Position of LoGetSelfData return,
+	// they return nil if it is nil.
+	FlightData *outputparser.FlightData
+
+	l        *lua.LState
+	writeDir fs.FS
+	files    map[string]*bytes.Buffer
+	logs     []LogMessage
+	sent     []Datagram
+	received []string
+	sockets  int
+}
+
+// New returns the harness that loads the scripts from writeDir, the DCS write directory with the Scripts folder.
+// The harness must be closed after use.
+func New(writeDir fs.FS) *Harness {
+	h := &Harness{
+		Arguments: make(map[int]float64),
+		l:         lua.NewState(),
+		writeDir:  writeDir,
+		files:     make(map[string]*bytes.Buffer),
+	}
+
+	h.l.SetGlobal("dofile", h.l.NewFunction(h.dofile))
+	h.l.SetGlobal("LoGetSelfData", h.l.NewFunction(h.loGetSelfData))
+	h.l.SetGlobal("GetDevice", h.l.NewFunction(h.getDevice))
+	h.setFlightDataFuncs()
+
+	lfs := h.lfs()
+	h.l.SetGlobal("lfs", lfs)
+	h.l.PreloadModule("lfs", func(l *lua.LState) int {
+		l.Push(lfs)
+		return 1
+	})
+
+	h.l.SetGlobal("log", h.log())
+	h.l.PreloadModule("socket", h.socket)
+
+	// gopher-lua supports neither the files opened in "wa" mode nor the files that are not on the disk
+	io := h.l.GetGlobal("io").(*lua.LTable)
+	h.l.SetField(io, "open", h.l.NewFunction(h.open))
+	h.l.SetField(io, "close", h.l.NewFunction(closeFile))
+
+	return h
+}
+
+// Close closes the interpreter.
+func (h *Harness) Close() {
+	h.l.Close()
+}
+
+// DoString runs the code the way DCS runs Export.lua, e.g. the line that loads the exporter.
+func (h *Harness) DoString(code string) error {
+	return h.l.DoString(code)
+}
+
+// Start calls LuaExportStart.
+func (h *Harness) Start() error {
+	_, err := h.call("LuaExportStart")
+	return err
+}
+
+// ActivityNextEvent calls LuaExportActivityNextEvent at the model time t and returns the time of the next event.
+func (h *Harness) ActivityNextEvent(t float64) (float64, error) {
+	ret, err := h.call("LuaExportActivityNextEvent", lua.LNumber(t))
+	if err != nil {
+		return 0, err
+	}
+
+	next, ok := ret.(lua.LNumber)
+	if !ok {
+		return 0, fmt.Errorf("LuaExportActivityNextEvent returned %s instead of number", ret.Type())
+	}
+
+	return float64(next), nil
+}
+
+// Stop calls LuaExportStop.
+func (h *Harness) Stop() error {
+	_, err := h.call("LuaExportStop")
+	return err
+}
+
+// Receive queues the datagram the sockets receive next.
+func (h *Harness) Receive(data string) {
+	h.received = append(h.received, data)
+}
+
+// TakeSent returns the datagrams sent since the previous call.
+func (h *Harness) TakeSent() []Datagram {
+	sent := h.sent
+	h.sent = nil
+
+	return sent
+}
+
+// Logs returns the messages written to the DCS log.
+func (h *Harness) Logs() []LogMessage {
+	return h.logs
+}
+
+// File returns the contents of the file the scripts wrote with io.open, the name is relative to the write directory.
+func (h *Harness) File(name string) string {
+	if b, ok := h.files[name]; ok {
+		return b.String()
+	}
+
+	return ""
+}
+
+// OpenSockets returns the number of the sockets that are not closed.
+func (h *Harness) OpenSockets() int {
+	return h.sockets
+}
+
+// call calls the global function and returns its first result.
+func (h *Harness) call(name string, args ...lua.LValue) (lua.LValue, error) {
+	fn := h.l.GetGlobal(name)
+	if fn.Type() != lua.LTFunction {
+		return nil, fmt.Errorf("%s is not defined", name)
+	}
+
+	if err := h.l.CallByParam(lua.P{Fn: fn, NRet: 1, Protect: true}, args...); err != nil {
+		return nil, fmt.Errorf("failed to call %s: %w", name, err)
+	}
+
+	ret := h.l.Get(-1)
+	h.l.Pop(1)
+
+	return ret, nil
+}
+
+// writeDirName returns the name in the write directory filesystem of the path under WriteDirPath.
+func writeDirName(p string) (string, error) {
+	p = strings.ReplaceAll(p, `\`, "/")
+	writeDir := strings.ReplaceAll(WriteDirPath, `\`, "/")
+
+	if !strings.HasPrefix(p, writeDir) {
+		return "", fmt.Errorf("path is outside of the write directory: '%s'", p)
+	}
+
+	return path.Clean(strings.TrimPrefix(p, writeDir)), nil
+}
+
+func (h *Harness) dofile(l *lua.LState) int {
+	name, err := writeDirName(l.CheckString(1))
+	if err != nil {
+		l.RaiseError("%s", err)
+	}
+
+	code, err := fs.ReadFile(h.writeDir, name)
+	if err != nil {
+		l.RaiseError("cannot open %s: %s", name, err)
+	}
+
+	fn, err := l.Load(bytes.NewReader(code), name)
+	if err != nil {
+		l.RaiseError("%s", err)
+	}
+
+	top := l.GetTop()
+	l.Push(fn)
+	l.Call(0, lua.MultRet)
+
+	return l.GetTop() - top
+}
+
+func (h *Harness) lfs() *lua.LTable {
+	return h.l.SetFuncs(h.l.NewTable(), map[string]lua.LGFunction{
+		"writedir": func(l *lua.LState) int {
+			l.Push(lua.LString(WriteDirPath))
+			return 1
+		},
+		"attributes": func(l *lua.LState) int {
+			name, err := writeDirName(l.CheckString(1))
+			if err != nil {
+				l.Push(lua.LNil)
+				return 1
+			}
+
+			info, err := fs.Stat(h.writeDir, name)
+			if err != nil {
+				l.Push(lua.LNil)
+				return 1
+			}
+
+			mode := "file"
+			if info.IsDir() {
+				mode = "directory"
+			}
+
+			attrs := l.NewTable()
+			l.SetField(attrs, "mode", lua.LString(mode))
+			l.SetField(attrs, "size", lua.LNumber(info.Size()))
+			l.Push(attrs)
+
+			return 1
+		},
+	})
+}
+
+func (h *Harness) log() *lua.LTable {
+	log := h.l.SetFuncs(h.l.NewTable(), map[string]lua.LGFunction{
+		"write": func(l *lua.LState) int {
+			h.logs = append(h.logs, LogMessage{
+				Subsystem: l.CheckString(1),
+				Level:     l.CheckInt(2),
+				Message:   l.ToString(3),
+			})
+
+			return 0
+		},
+	})
+
+	levels := map[string]int{
+		"ALERT":   LogAlert,
+		"ERROR":   LogError,
+		"WARNING": LogWarning,
+		"INFO":    LogInfo,
+		"DEBUG":   LogDebug,
+		"TRACE":   LogTrace,
+	}
+	for name, level := range levels {
+		h.l.SetField(log, name, lua.LNumber(level))
+	}
+
+	return log
+}
+
+func (h *Harness) loGetSelfData(l *lua.LState) int {
+	if h.Aircraft == "" {
+		l.Push(lua.LNil)
+		return 1
+	}
+
+	data := l.NewTable()
+	l.SetField(data, "Name", lua.LString(h.Aircraft))
+	if h.FlightData != nil {
+		l.SetField(data, "Heading", lua.LNumber(h.FlightData.Heading))
+		l.SetField(data, "Position", vec3Table(l, h.FlightData.Position))
+	}
+	l.Push(data)
+
+	return 1
+}
+
+func (h *Harness) getDevice(l *lua.LState) int {
+	if l.CheckInt(1) != 0 {
+		l.Push(lua.LNil)
+		return 1
+	}
+
+	l.Push(l.SetFuncs(l.NewTable(), map[string]lua.LGFunction{
+		"update_arguments": func(l *lua.LState) int { return 0 },
+		"get_argument_value": func(l *lua.LState) int {
+			l.Push(lua.LNumber(h.Arguments[l.CheckInt(2)]))
+			return 1
+		},
+	}))
+
+	return 1
+}
+
+// setFlightDataFuncs sets the LoGet* functions of the flight data.
+func (h *Harness) setFlightDataFuncs() {
+	number := func(get func(fd *outputparser.FlightData) float64) lua.LGFunction {
+		return func(l *lua.LState) int {
+			if h.FlightData == nil {
+				l.Push(lua.LNil)
+			} else {
+				l.Push(lua.LNumber(get(h.FlightData)))
+			}
+
+			return 1
+		}
+	}
+
+	vector := func(get func(fd *outputparser.FlightData) outputparser.Vec3) lua.LGFunction {
+		return func(l *lua.LState) int {
+			if h.FlightData == nil {
+				l.Push(lua.LNil)
+				return 1
+			}
+
+			l.Push(vec3Table(l, get(h.FlightData)))
+
+			return 1
+		}
+	}
+
+	h.l.SetFuncs(h.l.G.Global, map[string]lua.LGFunction{
+		"LoGetIndicatedAirSpeed":        number(func(fd *outputparser.FlightData) float64 { return fd.IndicatedAirspeed }),
+		"LoGetAltitudeAboveGroundLevel": number(func(fd *outputparser.FlightData) float64 { return fd.AltitudeAGL }),
+		"LoGetVectorVelocity":           vector(func(fd *outputparser.FlightData) outputparser.Vec3 { return fd.Velocity }),
+		"LoGetAccelerationUnits":        vector(func(fd *outputparser.FlightData) outputparser.Vec3 { return fd.Acceleration }),
+		"LoGetAngleOfAttack":            number(func(fd *outputparser.FlightData) float64 { return fd.AngleOfAttack }),
+		"LoGetSlipBallPosition":         number(func(fd *outputparser.FlightData) float64 { return fd.SlipBall }),
+	})
+}
+
+// open opens the in-memory file for writing, the file is appended to in any mode.
+func (h *Harness) open(l *lua.LState) int {
+	name, err := writeDirName(l.CheckString(1))
+	if err != nil {
+		l.Push(lua.LNil)
+		l.Push(lua.LString(err.Error()))
+
+		return 2
+	}
+
+	b, ok := h.files[name]
+	if !ok {
+		b = new(bytes.Buffer)
+		h.files[name] = b
+	}
+
+	l.Push(l.SetFuncs(l.NewTable(), map[string]lua.LGFunction{
+		"write": func(l *lua.LState) int {
+			for i := 2; i <= l.GetTop(); i++ {
+				b.WriteString(l.CheckString(i))
+			}
+
+			l.Push(l.Get(1))
+
+			return 1
+		},
+		"close": func(l *lua.LState) int {
+			l.Push(lua.LTrue)
+			return 1
+		},
+	}))
+
+	return 1
+}
+
+// closeFile implements io.close for the files returned by open.
+func closeFile(l *lua.LState) int {
+	f := l.CheckTable(1)
+
+	l.Push(f.RawGetString("close"))
+	l.Push(f)
+	l.Call(1, 1)
+
+	return 1
+}
+
+// socket is the loader of the LuaSocket module with the UDP sockets only.
+func (h *Harness) socket(l *lua.LState) int {
+	l.Push(l.SetFuncs(l.NewTable(), map[string]lua.LGFunction{
+		"udp": func(l *lua.LState) int {
+			l.Push(h.udp(l))
+			return 1
+		},
+	}))
+
+	return 1
+}
+
+// errSocketClosed is returned by the methods of the closed socket as LuaSocket does.
+var errSocketClosed = errors.New("closed")
+
+func (h *Harness) udp(l *lua.LState) *lua.LTable {
+	h.sockets++
+	closed := false
+
+	ok := func(l *lua.LState) int {
+		l.Push(lua.LNumber(1))
+		return 1
+	}
+
+	fail := func(l *lua.LState, err error) int {
+		l.Push(lua.LNil)
+		l.Push(lua.LString(err.Error()))
+
+		return 2
+	}
+
+	return l.SetFuncs(l.NewTable(), map[string]lua.LGFunction{
+		"setsockname": ok,
+		"setoption":   ok,
+		"settimeout":  ok,
+		"sendto": func(l *lua.LState) int {
+			if closed {
+				return fail(l, errSocketClosed)
+			}
+
+			data := l.CheckString(2)
+			h.sent = append(h.sent, Datagram{Data: []byte(data), Host: l.CheckString(3), Port: l.CheckInt(4)})
+			l.Push(lua.LNumber(len(data)))
+
+			return 1
+		},
+		"receive": func(l *lua.LState) int {
+			if closed {
+				return fail(l, errSocketClosed)
+			}
+
+			if len(h.received) == 0 {
+				return fail(l, errors.New("timeout"))
+			}
+
+			data := h.received[0]
+			h.received = h.received[1:]
+			l.Push(lua.LString(data))
+
+			return 1
+		},
+		"close": func(l *lua.LState) int {
+			if !closed {
+				closed = true
+				h.sockets--
+			}
+
+			return ok(l)
+		},
+	})
+}
+
+// vec3Table returns the vector as the table with x, y and z fields the DCS API returns.
+func vec3Table(l *lua.LState, v outputparser.Vec3) *lua.LTable {
+	t := l.NewTable()
+	l.SetField(t, "x", lua.LNumber(v.X))
+	l.SetField(t, "y", lua.LNumber(v.Y))
+	l.SetField(t, "z", lua.LNumber(v.Z))
+
+	return t
+}
@@ -0,0 +1,331 @@
+package exportertest_test
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimchansky/dcs-hmd/aircraft/ka-50/outputparser"
+	"github.com/dimchansky/dcs-hmd/exportertest"
+)
+
+// hookLine is the line the installer adds to Export.lua.
+const hookLine = "local lfs=require('lfs');dofile(lfs.writedir()..'Scripts/DCSHMD/Export.lua')"
+
+// values records the values set by the parser.
+type values map[string]float64
+
+func (v values) SetVerticalVelocity(val float64) { v["vertical velocity"] = val }
+func (v values) SetRotorPitch(val float64)       { v["rotor pitch"] = val }
+func (v values) SetRotorRPM(val float64)         { v["rotor RPM"] = val }
+
+// writeDir returns the DCS write directory with the scripts installed to the folder and the generated files.
+func writeDir(t *testing.T, folder string, generated map[string]string) fstest.MapFS {
+	t.Helper()
+
+	fsys := fstest.MapFS{}
+
+	scripts := os.DirFS("../scripts/DCSHMD")
+	err := fs.WalkDir(scripts, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		data, err := fs.ReadFile(scripts, name)
+		fsys[path.Join("Scripts", folder, name)] = &fstest.MapFile{Data: data}
+
+		return err
+	})
+	require.NoError(t, err)
+
+	for name, data := range generated {
+		fsys[path.Join("Scripts", folder, name)] = &fstest.MapFile{Data: []byte(data)}
+	}
+
+	return fsys
+}
+
+// start loads the exporter with the line of Export.lua and starts the mission with Ka-50 in the cockpit.
+func start(t *testing.T, fsys fs.FS, line string) *exportertest.Harness {
+	t.Helper()
+
+	h := exportertest.New(fsys)
+	t.Cleanup(h.Close)
+
+	h.Aircraft = "Ka-50"
+	h.Arguments[24] = 0.5
+	h.Arguments[52] = 0.7792
+	h.Arguments[53] = 0.9362
+
+	require.NoError(t, h.DoString(line))
+	require.NoError(t, h.Start())
+
+	return h
+}
+
+// parse handles the datagrams with the parser and returns the values it sets.
+func parse(p *outputparser.OutputParser, v values, datagrams []exportertest.Datagram) values {
+	clear(v)
+
+	for _, d := range datagrams {
+		p.HandleMessage(d.Data)
+	}
+
+	return v
+}
+
+// requireNoErrors checks that the exporter has not logged the errors.
+func requireNoErrors(t *testing.T, h *exportertest.Harness) {
+	t.Helper()
+
+	for _, m := range h.Logs() {
+		require.False(t, strings.HasPrefix(m.Subsystem, "ERROR"), m.String())
+	}
+}
+
+func TestExporter(t *testing.T) {
+	h := start(t, writeDir(t, "DCSHMD", nil), hookLine)
+
+	v := values{}
+	p := outputparser.New(v)
+
+	next, err := h.ActivityNextEvent(1)
+	require.NoError(t, err)
+	require.InDelta(t, 1.01, next, 1e-9)
+
+	sent := h.TakeSent()
+	require.Len(t, sent, 1)
+	require.Equal(t, "127.0.0.1", sent[0].Host)
+	require.Equal(t, 19089, sent[0].Port)
+	require.InDeltaMapValues(t, values{
+		"vertical velocity": 15,
+		"rotor pitch":       14.1068,
+		"rotor RPM":         85.712,
+	}, parse(p, v, sent), 1e-4)
+
+	// only the changed values are sent
+	_, err = h.ActivityNextEvent(next)
+	require.NoError(t, err)
+	require.Empty(t, h.TakeSent())
+
+	h.Arguments[52] = 0.8
+	_, err = h.ActivityNextEvent(next)
+	require.NoError(t, err)
+	require.InDeltaMapValues(t, values{"rotor RPM": 88}, parse(p, v, h.TakeSent()), 1e-4)
+
+	require.NoError(t, h.Stop())
+	require.Zero(t, h.OpenSockets())
+	requireNoErrors(t, h)
+
+	stats := p.Stats()
+	require.Equal(t, uint64(2), stats.Messages)
+	require.Zero(t, stats.ParseErrors)
+	require.Zero(t, stats.Sequence.LostPackets)
+	require.Contains(t, h.File("Logs/DCSHMDDebug.log"), "DCSHMD SOCKET CREATED!")
+}
+
+func TestExporter_Config(t *testing.T) {
+	const config = `DCSHMD_Config = {
+    Targets = {
+        { Host = "192.168.1.10", Port = 19090 },
+        { Host = "239.0.0.1", Port = 19091 },
+    },
+    Protocol = "binary",
+    Rates = { High = 50, Medium = 10, Low = 1 },
+    Aircraft = {
+        "Ka-50",
+    },
+}
+`
+	line := "local lfs=require('lfs');DCSHMD_Folder='HMD';dofile(lfs.writedir()..'Scripts/HMD/Export.lua')"
+	h := start(t, writeDir(t, "HMD", map[string]string{"Config.lua": config}), line)
+
+	next, err := h.ActivityNextEvent(1)
+	require.NoError(t, err)
+	require.InDelta(t, 1.02, next, 1e-9)
+
+	sent := h.TakeSent()
+	require.Len(t, sent, 2)
+	require.Equal(t, "192.168.1.10", sent[0].Host)
+	require.Equal(t, 19090, sent[0].Port)
+	require.Equal(t, "239.0.0.1", sent[1].Host)
+	require.Equal(t, 19091, sent[1].Port)
+	require.Equal(t, sent[0].Data, sent[1].Data)
+	require.True(t, outputparser.IsBinary(sent[0].Data))
+
+	v := values{}
+	p := outputparser.New(v)
+	require.InDeltaMapValues(t, values{
+		"vertical velocity": 15,
+		"rotor pitch":       14.1068,
+		"rotor RPM":         85.712,
+	}, parse(p, v, sent[:1]), 1e-4)
+
+	require.NoError(t, h.Stop())
+	requireNoErrors(t, h)
+}
+
+func TestExporter_TwoFolders(t *testing.T) {
+	fsys := writeDir(t, "DCSHMD", map[string]string{"Config.lua": `DCSHMD_Config = { Protocol = "binary" }`})
+	for name, f := range writeDir(t, "DCSHMD-Test", map[string]string{
+		"Config.lua": `DCSHMD_Config = { Targets = { { Host = "127.0.0.1", Port = 19090 } } }`,
+	}) {
+		fsys[name] = f
+	}
+
+	// the hook of the installation to the default folder is loaded after the one to the other folder
+	line := "local lfs=require('lfs');DCSHMD_Folder='DCSHMD-Test';dofile(lfs.writedir()..'Scripts/DCSHMD-Test/Export.lua')"
+	h := start(t, fsys, line+"\n"+hookLine)
+	require.Equal(t, 2, h.OpenSockets())
+
+	_, err := h.ActivityNextEvent(1)
+	require.NoError(t, err)
+
+	// every installation sends the values with its own configuration
+	sent := h.TakeSent()
+	require.Len(t, sent, 2)
+
+	binary := map[int]bool{}
+	for _, d := range sent {
+		binary[d.Port] = outputparser.IsBinary(d.Data)
+
+		v := values{}
+		require.InDeltaMapValues(t, values{
+			"vertical velocity": 15,
+			"rotor pitch":       14.1068,
+			"rotor RPM":         85.712,
+		}, parse(outputparser.New(v), v, []exportertest.Datagram{d}), 1e-4)
+	}
+
+	require.Equal(t, map[int]bool{19089: true, 19090: false}, binary)
+
+	require.NoError(t, h.Stop())
+	require.Zero(t, h.OpenSockets())
+	requireNoErrors(t, h)
+}
+
+func TestExporter_Refresh(t *testing.T) {
+	h := start(t, writeDir(t, "DCSHMD", map[string]string{"Config.lua": "DCSHMD_Config = { Rates = { High = 10, Medium = 5, Low = 1 } }"}), hookLine)
+
+	v := values{}
+	p := outputparser.New(v)
+
+	_, err := h.ActivityNextEvent(0)
+	require.NoError(t, err)
+	require.Len(t, parse(p, v, h.TakeSent()), 3)
+
+	// all the values are sent on the refresh request
+	h.Receive("refresh")
+
+	_, err = h.ActivityNextEvent(0)
+	require.NoError(t, err)
+	require.Len(t, parse(p, v, h.TakeSent()), 3)
+
+	// and in the keyframe about once a second
+	for i := 0; i < 9; i++ {
+		_, err = h.ActivityNextEvent(0)
+		require.NoError(t, err)
+		require.Empty(t, h.TakeSent(), i)
+	}
+
+	_, err = h.ActivityNextEvent(0)
+	require.NoError(t, err)
+	require.Len(t, parse(p, v, h.TakeSent()), 3)
+
+	requireNoErrors(t, h)
+}
+
+func TestExporter_Aircraft(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   map[string]string
+		aircraft string
+		sent     bool
+	}{
+		{"no aircraft", nil, "", false},
+		{"unsupported aircraft", nil, "Mi-8MT", false},
+		{"aircraft variant", nil, "Ka-50_3", true},
+		{"disabled aircraft", map[string]string{"Config.lua": "DCSHMD_Config = { Aircraft = {} }"}, "Ka-50", false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			h := start(t, writeDir(t, "DCSHMD", tt.config), hookLine)
+			h.Aircraft = tt.aircraft
+
+			_, err := h.ActivityNextEvent(0)
+			require.NoError(t, err)
+			require.Equal(t, tt.sent, len(h.TakeSent()) > 0)
+			requireNoErrors(t, h)
+		})
+	}
+}
+
+// flightData records the flight data set by the parser.
+type flightData struct {
+	values
+	fd *outputparser.FlightData
+}
+
+func (f *flightData) SetFlightData(fd outputparser.FlightData) { f.fd = &fd }
+
+func TestExporter_FlightData(t *testing.T) {
+	want := outputparser.FlightData{
+		IndicatedAirspeed: 41.666,
+		AltitudeAGL:       3.456,
+		Velocity:          outputparser.Vec3{X: 1.234, Y: -0.5, Z: 0.004},
+		Acceleration:      outputparser.Vec3{X: 0.0123, Y: 1.0456, Z: -0.05},
+		AngleOfAttack:     0.087266,
+		SlipBall:          -0.2504,
+		Heading:           4.71239,
+		Position:          outputparser.Vec3{X: -284500.254, Y: 12.5, Z: 683200.75},
+	}
+
+	for _, protocol := range []string{"text", "binary"} {
+		protocol := protocol
+		t.Run(protocol, func(t *testing.T) {
+			config := map[string]string{"Config.lua": `DCSHMD_Config = { Protocol = "` + protocol + `" }`}
+			h := start(t, writeDir(t, "DCSHMD", config), hookLine)
+
+			// the flight data is exported for every airframe
+			h.Aircraft = "Mi-8MT"
+			h.FlightData = &want
+
+			_, err := h.ActivityNextEvent(0)
+			require.NoError(t, err)
+
+			s := &flightData{values: values{}}
+			p := outputparser.New(s)
+
+			sent := h.TakeSent()
+			require.Len(t, sent, 1)
+			require.Empty(t, parse(p, s.values, sent), "no cockpit arguments of unsupported aircraft")
+			require.NotNil(t, s.fd)
+
+			got := *s.fd
+			require.InDelta(t, want.IndicatedAirspeed, got.IndicatedAirspeed, 0.005)
+			require.InDelta(t, want.AltitudeAGL, got.AltitudeAGL, 0.005)
+			require.InDelta(t, want.Velocity.X, got.Velocity.X, 0.005)
+			require.InDelta(t, want.Velocity.Y, got.Velocity.Y, 0.005)
+			require.InDelta(t, want.Velocity.Z, got.Velocity.Z, 0.005)
+			require.InDelta(t, want.Acceleration.X, got.Acceleration.X, 0.0005)
+			require.InDelta(t, want.Acceleration.Y, got.Acceleration.Y, 0.0005)
+			require.InDelta(t, want.Acceleration.Z, got.Acceleration.Z, 0.0005)
+			require.InDelta(t, want.AngleOfAttack, got.AngleOfAttack, 0.00005)
+			require.InDelta(t, want.SlipBall, got.SlipBall, 0.0005)
+			require.InDelta(t, want.Heading, got.Heading, 0.00005)
+			require.InDelta(t, want.Position.X, got.Position.X, 0.07)
+			require.InDelta(t, want.Position.Y, got.Position.Y, 0.005)
+			require.InDelta(t, want.Position.Z, got.Position.Z, 0.07)
+
+			require.Zero(t, p.Stats().UnknownArgs)
+			requireNoErrors(t, h)
+		})
+	}
+}
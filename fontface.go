@@ -2,40 +2,116 @@ package dcshmd
 
 import (
 	"fmt"
+	"image"
 	"image/color"
+	"os"
 	"strings"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/examples/resources/fonts"
 	"github.com/hajimehoshi/ebiten/v2/text"
 	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
 	"golang.org/x/image/font/opentype"
 )
 
-func NewFontFace(size, dpi int) (*FontFace, error) {
-	tt, err := opentype.Parse(fonts.PressStart2P_ttf)
+const (
+	defaultFontSize = 13
+	defaultFontDPI  = 72
+
+	// maxCachedTextRuns limits the number of rendered text runs kept by FontFace.
+	maxCachedTextRuns = 256
+)
+
+// FontConfig describes the font face used to draw HUD text and gauge labels.
+type FontConfig struct {
+	// Path is a path to TTF or OTF font file. The embedded Go Regular font is used if it is empty.
+	Path    string
+	Size    float64
+	DPI     float64
+	Hinting font.Hinting
+}
+
+// DefaultFontConfig returns the configuration of the default HUD font.
+func DefaultFontConfig() FontConfig {
+	return FontConfig{
+		Size:    defaultFontSize,
+		DPI:     defaultFontDPI,
+		Hinting: font.HintingFull,
+	}
+}
+
+// ParseFontHinting parses font hinting name: "none", "vertical" or "full".
+func ParseFontHinting(s string) (font.Hinting, error) {
+	switch strings.ToLower(s) {
+	case "none":
+		return font.HintingNone, nil
+	case "vertical":
+		return font.HintingVertical, nil
+	case "full":
+		return font.HintingFull, nil
+	default:
+		return font.HintingNone, fmt.Errorf("unknown font hinting: '%s'", s)
+	}
+}
+
+func NewFontFace(cfg *FontConfig) (*FontFace, error) {
+	fontName := "Go Regular"
+	fontData := goregular.TTF
+
+	if cfg.Path != "" {
+		data, err := os.ReadFile(cfg.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read font file '%s': %w", cfg.Path, err)
+		}
+
+		fontName = cfg.Path
+		fontData = data
+	}
+
+	tt, err := opentype.Parse(fontData)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse PressStart2P.ttf font: %w", err)
+		return nil, fmt.Errorf("failed to parse '%s' font: %w", fontName, err)
 	}
 
 	ff, err := opentype.NewFace(tt, &opentype.FaceOptions{
-		Size:    float64(size),
-		DPI:     float64(dpi),
-		Hinting: font.HintingFull,
+		Size:    cfg.Size,
+		DPI:     cfg.DPI,
+		Hinting: cfg.Hinting,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create new font face: %w", err)
 	}
 
 	return &FontFace{
-		fontBaseSize: size,
-		Face:         ff,
+		lineHeight: ff.Metrics().Height.Ceil(),
+		Face:       ff,
+		runs:       make(map[textRunKey]*textRun),
 	}, nil
 }
 
 type FontFace struct {
-	fontBaseSize int
+	lineHeight int
 	font.Face
+
+	// runs caches rendered lines of text, so that repeated strings are rasterized only once.
+	runs map[textRunKey]*textRun
+}
+
+type textRunKey struct {
+	str string
+	clr color.RGBA64
+}
+
+// textRun is a line of text rendered into an image, offset is the position of the image relative to the dot.
+type textRun struct {
+	img    *ebiten.Image
+	offset image.Point
+}
+
+func (h *FontFace) Close() error {
+	h.clearRuns()
+
+	return h.Face.Close()
 }
 
 func (h *FontFace) TextWidth(str string) int {
@@ -54,19 +130,19 @@ func (h *FontFace) TextWidth(str string) int {
 }
 
 func (h *FontFace) DrawText(rt *ebiten.Image, str string, x, y int, clr color.Color) {
-	offsetY := h.fontBaseSize
+	offsetY := h.lineHeight
 	for _, line := range strings.Split(str, "\n") {
 		y += offsetY
-		text.Draw(rt, line, h.Face, x, y, clr)
+		h.drawLine(rt, line, x, y, clr)
 	}
 }
 
 func (h *FontFace) DrawTextWithShadow(rt *ebiten.Image, str string, x, y int, clr color.Color) {
-	offsetY := h.fontBaseSize
+	offsetY := h.lineHeight
 	for _, line := range strings.Split(str, "\n") {
 		y += offsetY
-		text.Draw(rt, line, h.Face, x+1, y+1, shadowColor)
-		text.Draw(rt, line, h.Face, x, y, clr)
+		h.drawLine(rt, line, x+1, y+1, shadowColor)
+		h.drawLine(rt, line, x, y, clr)
 	}
 }
 
@@ -81,3 +157,53 @@ func (h *FontFace) DrawTextWithShadowRight(rt *ebiten.Image, str string, x, y in
 	x += width - w
 	h.DrawTextWithShadow(rt, str, x, y, clr)
 }
+
+// drawLine draws a single line of text with the dot at (x, y) using the cached text run.
+func (h *FontFace) drawLine(rt *ebiten.Image, line string, x, y int, clr color.Color) {
+	run := h.getRun(line, clr)
+	if run == nil {
+		return
+	}
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(x+run.offset.X), float64(y+run.offset.Y))
+	rt.DrawImage(run.img, op)
+}
+
+// getRun returns rendered text run for the line, it returns nil if there is nothing to draw.
+func (h *FontFace) getRun(line string, clr color.Color) *textRun {
+	r, g, b, a := clr.RGBA()
+	key := textRunKey{str: line, clr: color.RGBA64{R: uint16(r), G: uint16(g), B: uint16(b), A: uint16(a)}}
+
+	if run, ok := h.runs[key]; ok {
+		return run
+	}
+
+	bounds, _ := font.BoundString(h.Face, line)
+	minX, minY := bounds.Min.X.Floor(), bounds.Min.Y.Floor()
+	width, height := bounds.Max.X.Ceil()-minX, bounds.Max.Y.Ceil()-minY
+
+	if width <= 0 || height <= 0 {
+		return nil
+	}
+
+	img := ebiten.NewImage(width, height)
+	text.Draw(img, line, h.Face, -minX, -minY, clr)
+
+	if len(h.runs) >= maxCachedTextRuns {
+		// the strings drawn on HUD are mostly the same, so simply start over when the cache is full
+		h.clearRuns()
+	}
+
+	run := &textRun{img: img, offset: image.Pt(minX, minY)}
+	h.runs[key] = run
+
+	return run
+}
+
+func (h *FontFace) clearRuns() {
+	for key, run := range h.runs {
+		run.img.Dispose()
+		delete(h.runs, key)
+	}
+}
@@ -0,0 +1,144 @@
+package gmeter
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/fogleman/gg"
+	"github.com/hajimehoshi/ebiten/v2"
+	"golang.org/x/image/font"
+)
+
+type Config struct {
+	Width       int
+	Height      int
+	Color       color.NRGBA
+	BorderColor color.NRGBA
+	// FontFace is used to draw the G-load, gg's default font is used if it is nil.
+	FontFace font.Face
+}
+
+// New creates the G meter: the G-load readout with one decimal place, e.g. "1.2G".
+func New(cfg *Config) *Indicator {
+	i := &Indicator{}
+	i.SetG(1)
+	i.Rebuild(cfg)
+
+	return i
+}
+
+// Rebuild regenerates the indicator image for the new dimensions in cfg, e.g. when the screen resolution changes.
+// Like GetImage, it must not be called concurrently with itself or GetImage.
+func (i *Indicator) Rebuild(cfg *Config) {
+	if i.finalImg != nil {
+		i.finalImg.Dispose()
+	}
+
+	dc := gg.NewContext(cfg.Width, cfg.Height)
+	if cfg.FontFace != nil {
+		dc.SetFontFace(cfg.FontFace)
+	}
+
+	i.finalImg = ebiten.NewImage(cfg.Width, cfg.Height)
+	i.dc = dc
+	i.cfg = *cfg
+
+	i.redrawFinalImage(tenths(i.GetG()))
+}
+
+type Indicator struct {
+	rwMutex sync.RWMutex
+
+	// images
+	finalImg *ebiten.Image
+	dc       *gg.Context
+
+	cfg Config
+
+	// drawn state, the G-load in tenths of G
+	drawnTenths int
+
+	// thread-safe
+	g float64
+}
+
+// SetG sets the G-load.
+func (i *Indicator) SetG(g float64) {
+	m := &i.rwMutex
+	m.Lock()
+	i.g = g
+	m.Unlock()
+}
+
+func (i *Indicator) GetG() (g float64) {
+	m := &i.rwMutex
+	m.RLock()
+	g = i.g
+	m.RUnlock()
+
+	return
+}
+
+func (i *Indicator) GetImage() (img *ebiten.Image, isRedrawn bool) {
+	// optimization: redraw the final image only if the readout has changed
+	if t := tenths(i.GetG()); t != i.drawnTenths {
+		i.redrawFinalImage(t)
+
+		isRedrawn = true
+	}
+
+	img = i.finalImg
+
+	return
+}
+
+// tenths returns the G-load rounded to tenths of G.
+func tenths(g float64) int {
+	return int(math.Round(g * 10))
+}
+
+// label returns the readout of the G-load given in tenths of G.
+func label(t int) string {
+	return strconv.FormatFloat(float64(t)/10, 'f', 1, 64) + "G"
+}
+
+func (i *Indicator) redrawFinalImage(t int) {
+	dc := i.dc
+	cfg := &i.cfg
+
+	dc.SetColor(color.Transparent)
+	dc.Clear()
+
+	text := label(t)
+	x := float64(cfg.Width) / 2
+	y := float64(cfg.Height) / 2
+
+	dc.SetColor(cfg.BorderColor)
+
+	const (
+		n  = 3 // "stroke" size
+		ax = 0.5
+		ay = 0.4
+	)
+
+	for dy := -n; dy <= n; dy++ {
+		for dx := -n; dx <= n; dx++ {
+			if dx*dx+dy*dy >= n*n {
+				// give it rounded corners
+				continue
+			}
+
+			dc.DrawStringAnchored(text, x+float64(dx), y+float64(dy), ax, ay)
+		}
+	}
+	dc.SetColor(cfg.Color)
+	dc.DrawStringAnchored(text, x, y, ax, ay)
+
+	i.finalImg.WritePixels(dc.Image().(*image.RGBA).Pix)
+
+	// update the G-load for which the final image is rendered
+	i.drawnTenths = t
+}
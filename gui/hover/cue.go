@@ -0,0 +1,121 @@
+package hover
+
+import (
+	"time"
+
+	"github.com/dimchansky/dcs-hmd/aircraft/ka-50/outputparser"
+)
+
+const (
+	// accelerationTimeConstant is the time constant of the low-pass filter of the acceleration estimate,
+	// it smooths the jitter of the packet arrival times.
+	accelerationTimeConstant = 500 * time.Millisecond
+
+	// maxUpdateInterval is the longest interval between the updates the acceleration is estimated over,
+	// the estimate is restarted after the longer gap.
+	maxUpdateInterval = 500 * time.Millisecond
+)
+
+// Vec2 is the horizontal vector in the aircraft heading frame: Forward is along the heading and Right is
+// perpendicular to it.
+type Vec2 struct {
+	Forward, Right float64
+}
+
+// Cue is the hover cue in the aircraft heading frame.
+type Cue struct {
+	// Velocity is the drift over the ground in m/s.
+	Velocity Vec2
+	// Acceleration is the horizontal acceleration in m/s², it shows where the drift is going.
+	Acceleration Vec2
+	// Target is the offset of the hover point from the aircraft in meters, it is valid if HasTarget is true.
+	Target    Vec2
+	HasTarget bool
+}
+
+// Tracker computes the hover cue from the flight data. The acceleration is estimated from the changes of the
+// velocity, since the accelerometer of the hovering helicopter senses the rotor thrust rather than the drift.
+// It must not be used concurrently.
+type Tracker struct {
+	// last update
+	time     time.Time
+	flight   outputparser.FlightData
+	hasData  bool
+	hasAccel bool
+
+	// acceleration in the world frame: north and east, m/s²
+	accelNorth, accelEast float64
+
+	// target hover point in the world frame: north and east, m
+	targetNorth, targetEast float64
+	hasTarget               bool
+}
+
+// Update updates the tracker with the flight data received at the time.
+func (t *Tracker) Update(now time.Time, fd *outputparser.FlightData) {
+	dt := now.Sub(t.time)
+
+	switch {
+	case !t.hasData || dt < 0 || dt > maxUpdateInterval:
+		// the first update, the clock went backwards or the data has been lost for a while
+		t.resetAcceleration()
+	case dt > 0:
+		seconds := dt.Seconds()
+		accelNorth := (fd.Velocity.X - t.flight.Velocity.X) / seconds
+		accelEast := (fd.Velocity.Z - t.flight.Velocity.Z) / seconds
+
+		if t.hasAccel {
+			alpha := seconds / (accelerationTimeConstant.Seconds() + seconds)
+			t.accelNorth += alpha * (accelNorth - t.accelNorth)
+			t.accelEast += alpha * (accelEast - t.accelEast)
+		} else {
+			t.accelNorth, t.accelEast = accelNorth, accelEast
+			t.hasAccel = true
+		}
+	}
+
+	t.time = now
+	t.flight = *fd
+	t.hasData = true
+}
+
+func (t *Tracker) resetAcceleration() {
+	t.accelNorth, t.accelEast = 0, 0
+	t.hasAccel = false
+}
+
+// SetTarget sets the target hover point at the current position of the aircraft, it reports false if there
+// is no flight data yet.
+func (t *Tracker) SetTarget() bool {
+	if !t.hasData {
+		return false
+	}
+
+	t.targetNorth = t.flight.Position.X
+	t.targetEast = t.flight.Position.Z
+	t.hasTarget = true
+
+	return true
+}
+
+// ClearTarget removes the target hover point.
+func (t *Tracker) ClearTarget() {
+	t.hasTarget = false
+}
+
+// Cue returns the hover cue for the last update.
+func (t *Tracker) Cue() Cue {
+	heading := t.flight.Heading
+
+	var c Cue
+	c.Velocity.Forward, c.Velocity.Right = t.flight.GroundVelocity()
+	c.Acceleration.Forward, c.Acceleration.Right = outputparser.HeadingFrame(t.accelNorth, t.accelEast, heading)
+
+	if t.hasTarget {
+		c.Target.Forward, c.Target.Right = outputparser.HeadingFrame(
+			t.targetNorth-t.flight.Position.X, t.targetEast-t.flight.Position.Z, heading)
+		c.HasTarget = true
+	}
+
+	return c
+}
@@ -0,0 +1,138 @@
+package hover_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimchansky/dcs-hmd/aircraft/ka-50/outputparser"
+	"github.com/dimchansky/dcs-hmd/gui/hover"
+)
+
+var start = time.Date(2023, 11, 21, 20, 0, 0, 0, time.UTC)
+
+func requireVec2(t *testing.T, want, got hover.Vec2, delta float64) {
+	t.Helper()
+
+	require.InDelta(t, want.Forward, got.Forward, delta, "forward")
+	require.InDelta(t, want.Right, got.Right, delta, "right")
+}
+
+func TestTracker_Target(t *testing.T) {
+	tests := []struct {
+		name    string
+		moved   outputparser.Vec3
+		heading float64
+		target  hover.Vec2
+	}{
+		{"in place", outputparser.Vec3{}, 1, hover.Vec2{}},
+		{"moved north heading north", outputparser.Vec3{X: 10}, 0, hover.Vec2{Forward: -10}},
+		{"moved east heading north", outputparser.Vec3{Z: 4}, 0, hover.Vec2{Right: -4}},
+		{"moved north heading east", outputparser.Vec3{X: 10}, math.Pi / 2, hover.Vec2{Right: 10}},
+		{"moved south heading west", outputparser.Vec3{X: -3}, 3 * math.Pi / 2, hover.Vec2{Right: 3}},
+		{"moved north-west heading south", outputparser.Vec3{X: 2, Z: -5}, math.Pi, hover.Vec2{Forward: 2, Right: -5}},
+		{"altitude is ignored", outputparser.Vec3{Y: 50}, 2, hover.Vec2{}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			position := outputparser.Vec3{X: -284500, Y: 10, Z: 683200}
+
+			var tr hover.Tracker
+			tr.Update(start, &outputparser.FlightData{Position: position, Heading: 0.3})
+			require.True(t, tr.SetTarget())
+
+			position.X += tt.moved.X
+			position.Y += tt.moved.Y
+			position.Z += tt.moved.Z
+			tr.Update(start.Add(time.Second), &outputparser.FlightData{Position: position, Heading: tt.heading})
+
+			cue := tr.Cue()
+			require.True(t, cue.HasTarget)
+			requireVec2(t, tt.target, cue.Target, 1e-9)
+		})
+	}
+}
+
+func TestTracker_TargetWithoutData(t *testing.T) {
+	var tr hover.Tracker
+	require.False(t, tr.SetTarget())
+	require.False(t, tr.Cue().HasTarget)
+
+	tr.Update(start, &outputparser.FlightData{})
+	require.True(t, tr.SetTarget())
+	require.True(t, tr.Cue().HasTarget)
+
+	tr.ClearTarget()
+	require.False(t, tr.Cue().HasTarget)
+}
+
+func TestTracker_Velocity(t *testing.T) {
+	var tr hover.Tracker
+	tr.Update(start, &outputparser.FlightData{Velocity: outputparser.Vec3{X: 1, Y: -2, Z: 3}, Heading: math.Pi / 2})
+
+	requireVec2(t, hover.Vec2{Forward: 3, Right: -1}, tr.Cue().Velocity, 1e-9)
+}
+
+// accelerate updates the tracker with the velocity changing with the acceleration in m/s² for the duration.
+func accelerate(tr *hover.Tracker, now time.Time, fd *outputparser.FlightData, north, east float64, d time.Duration) time.Time {
+	const step = 10 * time.Millisecond
+
+	for end := now.Add(d); now.Before(end); {
+		now = now.Add(step)
+		fd.Velocity.X += north * step.Seconds()
+		fd.Velocity.Z += east * step.Seconds()
+		tr.Update(now, fd)
+	}
+
+	return now
+}
+
+func TestTracker_Acceleration(t *testing.T) {
+	var tr hover.Tracker
+
+	fd := &outputparser.FlightData{Heading: math.Pi / 2}
+	now := start
+	tr.Update(now, fd)
+	requireVec2(t, hover.Vec2{}, tr.Cue().Acceleration, 1e-9)
+
+	// accelerating to the north while heading east is accelerating to the left
+	now = accelerate(&tr, now, fd, 1, 0, 5*time.Second)
+	requireVec2(t, hover.Vec2{Right: -1}, tr.Cue().Acceleration, 1e-6)
+
+	// the estimate follows the change of the acceleration
+	now = accelerate(&tr, now, fd, 0, 0.5, 5*time.Second)
+	requireVec2(t, hover.Vec2{Forward: 0.5}, tr.Cue().Acceleration, 1e-3)
+
+	// the jump of the velocity after the gap isn't taken as the acceleration
+	fd.Velocity.X += 5
+	tr.Update(now.Add(2*time.Second), fd)
+	requireVec2(t, hover.Vec2{}, tr.Cue().Acceleration, 1e-9)
+}
+
+func TestTracker_AccelerationFilter(t *testing.T) {
+	var tr hover.Tracker
+
+	fd := &outputparser.FlightData{}
+	now := start
+	tr.Update(now, fd)
+
+	// the first estimate is taken as is
+	now = accelerate(&tr, now, fd, 2, 0, 10*time.Millisecond)
+	requireVec2(t, hover.Vec2{Forward: 2}, tr.Cue().Acceleration, 1e-9)
+
+	// the single spike is smoothed
+	fd.Velocity.X += 1
+	tr.Update(now.Add(10*time.Millisecond), fd)
+
+	got := tr.Cue().Acceleration.Forward
+	require.Greater(t, got, 2.0)
+	require.Less(t, got, 5.0)
+
+	// the duplicate update doesn't change the estimate
+	tr.Update(now.Add(10*time.Millisecond), fd)
+	require.Equal(t, got, tr.Cue().Acceleration.Forward)
+}
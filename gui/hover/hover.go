@@ -0,0 +1,204 @@
+package hover
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/dimchansky/dcs-hmd/aircraft/ka-50/outputparser"
+)
+
+type Config struct {
+	// Radius is the radius of the display in pixels, the symbols beyond it are clipped to its edge.
+	Radius      int
+	SymbolSize  float64
+	LineWidth   float64
+	Color       color.NRGBA
+	BorderColor color.NRGBA
+	// MaxSpeed is the drift speed in m/s drawn at Radius.
+	MaxSpeed float64
+	// AccelerationLead is the time in seconds the acceleration cue leads the velocity vector by: the cue is
+	// drawn at the velocity the aircraft will have after that time.
+	AccelerationLead float64
+	// TargetRange is the distance to the target hover point in meters drawn at Radius.
+	TargetRange float64
+}
+
+// New creates the hover display: the drift velocity vector from the aircraft reference cross in the center,
+// the acceleration cue that leads the tip of the vector and the target hover point. Forward is up and right
+// is right, as on the ground velocity vector.
+func New(cfg *Config) *Indicator {
+	i := &Indicator{}
+	i.Rebuild(cfg)
+
+	return i
+}
+
+// Rebuild regenerates the indicator image for the new dimensions in cfg, e.g. when the screen resolution changes.
+// Like GetImage, it must not be called concurrently with itself or GetImage.
+func (i *Indicator) Rebuild(cfg *Config) {
+	margin := int(math.Ceil(cfg.SymbolSize + cfg.LineWidth*2))
+	size := 2 * (cfg.Radius + margin)
+
+	if i.finalImg != nil {
+		i.finalImg.Dispose()
+	}
+
+	i.finalImg = ebiten.NewImage(size, size)
+	i.cfg = *cfg
+	i.center = float32(size) / 2
+
+	i.redrawFinalImage(i.symbols(i.GetCue()))
+}
+
+type Indicator struct {
+	rwMutex sync.RWMutex
+
+	// images
+	finalImg *ebiten.Image
+
+	// image transformation variables
+	cfg    Config
+	center float32
+
+	// drawn state
+	drawnSymbols symbols
+
+	// thread-safe
+	tracker Tracker
+}
+
+// symbols holds the offsets of the symbols from the center in pixels.
+type symbols struct {
+	velocity     image.Point
+	acceleration image.Point
+	target       image.Point
+	hasTarget    bool
+}
+
+// SetFlightData updates the hover cue with the flight data received now.
+func (i *Indicator) SetFlightData(fd outputparser.FlightData) {
+	m := &i.rwMutex
+	m.Lock()
+	i.tracker.Update(time.Now(), &fd)
+	m.Unlock()
+}
+
+// SetTarget sets the target hover point at the current position of the aircraft, it reports false if there
+// is no flight data yet.
+func (i *Indicator) SetTarget() (ok bool) {
+	m := &i.rwMutex
+	m.Lock()
+	ok = i.tracker.SetTarget()
+	m.Unlock()
+
+	return
+}
+
+// ClearTarget removes the target hover point.
+func (i *Indicator) ClearTarget() {
+	m := &i.rwMutex
+	m.Lock()
+	i.tracker.ClearTarget()
+	m.Unlock()
+}
+
+func (i *Indicator) GetCue() (cue Cue) {
+	m := &i.rwMutex
+	m.RLock()
+	cue = i.tracker.Cue()
+	m.RUnlock()
+
+	return
+}
+
+func (i *Indicator) GetImage() (img *ebiten.Image, isRedrawn bool) {
+	// optimization: redraw the final image only if any symbol has moved to another pixel
+	if s := i.symbols(i.GetCue()); s != i.drawnSymbols {
+		i.redrawFinalImage(s)
+
+		isRedrawn = true
+	}
+
+	img = i.finalImg
+
+	return
+}
+
+// symbols returns the positions of the symbols of the cue.
+func (i *Indicator) symbols(cue Cue) symbols {
+	cfg := &i.cfg
+	speedScale := float64(cfg.Radius) / cfg.MaxSpeed
+
+	lead := Vec2{
+		Forward: cue.Velocity.Forward + cue.Acceleration.Forward*cfg.AccelerationLead,
+		Right:   cue.Velocity.Right + cue.Acceleration.Right*cfg.AccelerationLead,
+	}
+
+	return symbols{
+		velocity:     i.offset(cue.Velocity, speedScale),
+		acceleration: i.offset(lead, speedScale),
+		target:       i.offset(cue.Target, float64(cfg.Radius)/cfg.TargetRange),
+		hasTarget:    cue.HasTarget,
+	}
+}
+
+// offset returns the offset of the vector scaled to pixels from the center, it is clipped to the radius.
+func (i *Indicator) offset(v Vec2, scale float64) image.Point {
+	radius := float64(i.cfg.Radius)
+	x := v.Right * scale
+	y := -v.Forward * scale
+
+	if length := math.Hypot(x, y); length > radius {
+		x *= radius / length
+		y *= radius / length
+	}
+
+	return image.Pt(int(math.Round(x)), int(math.Round(y)))
+}
+
+func (i *Indicator) redrawFinalImage(s symbols) {
+	finalImg := i.finalImg
+	finalImg.Clear()
+
+	cfg := &i.cfg
+	c := i.center
+	size := float32(cfg.SymbolSize)
+	velocityX, velocityY := c+float32(s.velocity.X), c+float32(s.velocity.Y)
+	accelerationX, accelerationY := c+float32(s.acceleration.X), c+float32(s.acceleration.Y)
+	targetX, targetY := c+float32(s.target.X), c+float32(s.target.Y)
+
+	// the border is drawn under the lines, so that the symbols are visible on the bright background
+	for _, stroke := range []struct {
+		width float32
+		color color.NRGBA
+	}{
+		{float32(cfg.LineWidth * 3), cfg.BorderColor},
+		{float32(cfg.LineWidth), cfg.Color},
+	} {
+		// aircraft reference cross
+		vector.StrokeLine(finalImg, c-size, c, c+size, c, stroke.width, stroke.color, true)
+		vector.StrokeLine(finalImg, c, c-size, c, c+size, stroke.width, stroke.color, true)
+
+		// target hover point, the aircraft is over it when the square is on the cross
+		if s.hasTarget {
+			vector.StrokeRect(finalImg, targetX-size, targetY-size, 2*size, 2*size, stroke.width, stroke.color, true)
+		}
+
+		// drift velocity vector
+		if s.velocity != (image.Point{}) {
+			vector.StrokeLine(finalImg, c, c, velocityX, velocityY, stroke.width, stroke.color, true)
+		}
+
+		// acceleration cue: the circle the tip of the velocity vector is moving to
+		vector.StrokeCircle(finalImg, accelerationX, accelerationY, size/2, stroke.width, stroke.color, true)
+	}
+
+	// update the symbols for which the final image is rendered
+	i.drawnSymbols = s
+}
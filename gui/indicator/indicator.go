@@ -9,6 +9,7 @@ import (
 
 	"github.com/fogleman/gg"
 	"github.com/hajimehoshi/ebiten/v2"
+	"golang.org/x/image/font"
 
 	"github.com/dimchansky/dcs-hmd/utils"
 )
@@ -32,9 +33,28 @@ type Config struct {
 	MaxAllowedValue     *int
 	MinLabelStep        int
 	GetLabelOffset      func(value int) float64
+	// FontFace is used to draw labels, gg's default font is used if it is nil.
+	FontFace font.Face
 }
 
 func New(cfg *Config) *Indicator {
+	i := &Indicator{
+		valueRange: utils.Interval{
+			Start: float64(cfg.MinValue),
+			End:   float64(cfg.MaxValue),
+		},
+	}
+
+	i.SetValue(i.valueRange.Start)
+	i.Rebuild(cfg)
+
+	return i
+}
+
+// Rebuild regenerates the indicator images for the new dimensions in cfg, e.g. when the screen resolution changes.
+// The value range of cfg must be the same as the one the indicator was created with.
+// Like GetImage, it must not be called concurrently with itself or GetImage.
+func (i *Indicator) Rebuild(cfg *Config) {
 	width := cfg.Width
 	height := cfg.Height
 
@@ -110,6 +130,10 @@ func New(cfg *Config) *Indicator {
 	dc.Stroke()
 
 	// draw labels
+	if cfg.FontFace != nil {
+		dc.SetFontFace(cfg.FontFace)
+	}
+
 	for labelValue := cfg.MinValue; labelValue <= cfg.MaxValue; labelValue += cfg.MinLabelStep {
 		label := strconv.Itoa(labelValue)
 		x := verticalLineX - cfg.GetLabelOffset(labelValue)
@@ -166,25 +190,29 @@ func New(cfg *Config) *Indicator {
 
 	handImg := ebiten.NewImageFromImage(dc.Image())
 
-	i := &Indicator{
-		finalImg:                   ebiten.NewImage(width, height),
-		gaugeImg:                   gaugeImg,
-		handImg:                    handImg,
-		handPoint:                  handPoint,
-		verticalLineX:              verticalLineX,
-		maxFixedWindowValue:        float64(cfg.MaxFixedWindowValue),
-		minFixedWindowValue:        float64(cfg.MinFixedWindowValue),
-		valueToScreenY:             valueToScreenY,
-		maxValueScreenY:            valueToScreenY.TransformForward(float64(cfg.MaxValue)),
-		maxFixedWindowValueScreenY: valueToScreenY.TransformForward(float64(cfg.MaxFixedWindowValue)),
-		fixedWindowScreenHeight:    valueToScreenY.TransformForward(float64(cfg.MinFixedWindowValue)) - valueToScreenY.TransformForward(float64(cfg.MaxFixedWindowValue)),
-	}
-
-	currentValue := valueToScreenY.IntervalFrom.Start
-	i.SetValue(currentValue)
-	i.redrawFinalImage(currentValue)
+	i.disposeImages()
+
+	i.finalImg = ebiten.NewImage(width, height)
+	i.gaugeImg = gaugeImg
+	i.handImg = handImg
+	i.handPoint = handPoint
+	i.verticalLineX = verticalLineX
+	i.maxFixedWindowValue = float64(cfg.MaxFixedWindowValue)
+	i.minFixedWindowValue = float64(cfg.MinFixedWindowValue)
+	i.valueToScreenY = valueToScreenY
+	i.maxValueScreenY = valueToScreenY.TransformForward(float64(cfg.MaxValue))
+	i.maxFixedWindowValueScreenY = valueToScreenY.TransformForward(float64(cfg.MaxFixedWindowValue))
+	i.fixedWindowScreenHeight = valueToScreenY.TransformForward(float64(cfg.MinFixedWindowValue)) - valueToScreenY.TransformForward(float64(cfg.MaxFixedWindowValue))
+
+	i.redrawFinalImage(i.GetValue())
+}
 
-	return i
+func (i *Indicator) disposeImages() {
+	for _, img := range []*ebiten.Image{i.finalImg, i.gaugeImg, i.handImg} {
+		if img != nil {
+			img.Dispose()
+		}
+	}
 }
 
 type Indicator struct {
@@ -200,20 +228,22 @@ type Indicator struct {
 	verticalLineX  float64
 	valueToScreenY *utils.IntervalTransformer
 
-	// drawn state
-	drawnValue float64
-
-	// thread-safe
 	maxFixedWindowValue        float64
 	minFixedWindowValue        float64
-	valueToDraw                float64
 	maxValueScreenY            float64
 	maxFixedWindowValueScreenY float64
 	fixedWindowScreenHeight    float64
+
+	// drawn state
+	drawnValue float64
+
+	// thread-safe
+	valueRange  utils.Interval
+	valueToDraw float64
 }
 
 func (i *Indicator) SetValue(value float64) {
-	value = i.valueToScreenY.IntervalFrom.Sat(value)
+	value = i.valueRange.Sat(value)
 
 	m := &i.rwMutex
 	m.Lock()
@@ -0,0 +1,142 @@
+package slipindicator
+
+import (
+	"image/color"
+	"math"
+	"sync"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/dimchansky/dcs-hmd/utils"
+)
+
+type Config struct {
+	Width       int
+	Height      int
+	LineWidth   float64
+	Color       color.NRGBA
+	BorderColor color.NRGBA
+}
+
+// New creates the slip/skid indicator: the ball between two reference lines that moves along the tube,
+// the ball centered between the lines means the coordinated flight.
+func New(cfg *Config) *Indicator {
+	i := &Indicator{
+		valueRange: utils.Interval{Start: -1, End: 1},
+	}
+
+	i.Rebuild(cfg)
+
+	return i
+}
+
+// Rebuild regenerates the indicator image for the new dimensions in cfg, e.g. when the screen resolution changes.
+// Like GetImage, it must not be called concurrently with itself or GetImage.
+func (i *Indicator) Rebuild(cfg *Config) {
+	if i.finalImg != nil {
+		i.finalImg.Dispose()
+	}
+
+	i.finalImg = ebiten.NewImage(cfg.Width, cfg.Height)
+	i.cfg = *cfg
+
+	// the ball fits the height of the tube, it stops at the ends of the tube
+	margin := cfg.LineWidth * 2
+	i.ballRadius = float64(cfg.Height)/2 - margin
+	i.valueToScreenX = &utils.IntervalTransformer{
+		IntervalFrom: i.valueRange,
+		IntervalTo: utils.Interval{
+			Start: margin + i.ballRadius,
+			End:   float64(cfg.Width) - margin - i.ballRadius,
+		},
+	}
+
+	i.redrawFinalImage(i.ballX(i.GetSlip()))
+}
+
+type Indicator struct {
+	rwMutex sync.RWMutex
+
+	// images
+	finalImg *ebiten.Image
+
+	// image transformation variables
+	cfg            Config
+	ballRadius     float64
+	valueToScreenX *utils.IntervalTransformer
+
+	// drawn state
+	drawnBallX int
+
+	// thread-safe
+	valueRange utils.Interval
+	slip       float64
+}
+
+// SetSlip sets the slip ball position from -1 (full left) to 1 (full right).
+func (i *Indicator) SetSlip(slip float64) {
+	slip = i.valueRange.Sat(slip)
+
+	m := &i.rwMutex
+	m.Lock()
+	i.slip = slip
+	m.Unlock()
+}
+
+func (i *Indicator) GetSlip() (slip float64) {
+	m := &i.rwMutex
+	m.RLock()
+	slip = i.slip
+	m.RUnlock()
+
+	return
+}
+
+func (i *Indicator) GetImage() (img *ebiten.Image, isRedrawn bool) {
+	// optimization: redraw the final image only if the ball has moved to another pixel
+	if ballX := i.ballX(i.GetSlip()); ballX != i.drawnBallX {
+		i.redrawFinalImage(ballX)
+
+		isRedrawn = true
+	}
+
+	img = i.finalImg
+
+	return
+}
+
+// ballX returns the screen X coordinate of the ball center.
+func (i *Indicator) ballX(slip float64) int {
+	return int(math.Round(i.valueToScreenX.TransformForward(slip)))
+}
+
+func (i *Indicator) redrawFinalImage(ballX int) {
+	finalImg := i.finalImg
+	finalImg.Clear()
+
+	cfg := &i.cfg
+	centerX := float32(cfg.Width) / 2
+	centerY := float32(cfg.Height) / 2
+	r := float32(i.ballRadius)
+	lineSpan := r + float32(cfg.LineWidth)*2
+	top := float32(cfg.LineWidth)
+	bottom := float32(cfg.Height) - top
+
+	for _, stroke := range []struct {
+		width float32
+		color color.NRGBA
+	}{
+		{float32(cfg.LineWidth * 3), cfg.BorderColor},
+		{float32(cfg.LineWidth), cfg.Color},
+	} {
+		// reference lines the ball is centered between in the coordinated flight
+		vector.StrokeLine(finalImg, centerX-lineSpan, top, centerX-lineSpan, bottom, stroke.width, stroke.color, true)
+		vector.StrokeLine(finalImg, centerX+lineSpan, top, centerX+lineSpan, bottom, stroke.width, stroke.color, true)
+
+		vector.StrokeCircle(finalImg, float32(ballX), centerY, r, stroke.width, stroke.color, true)
+	}
+
+	// update the ball position for which the final image is rendered
+	i.drawnBallX = ballX
+}
@@ -0,0 +1,149 @@
+package velocityvector
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sync"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+type Config struct {
+	// Radius is the length of the vector in pixels at MaxSpeed, the faster vector is clipped to it.
+	Radius int
+	// TipRadius is the radius of the circle at the tip of the vector in pixels.
+	TipRadius   float64
+	CrossSize   float64
+	LineWidth   float64
+	Color       color.NRGBA
+	BorderColor color.NRGBA
+	// MaxSpeed is the ground speed in m/s drawn at Radius.
+	MaxSpeed float64
+}
+
+// New creates the ground velocity vector: the line from the aircraft reference cross in the center to the direction
+// of the drift over the ground, forward is up and right is right.
+func New(cfg *Config) *Indicator {
+	i := &Indicator{}
+	i.Rebuild(cfg)
+
+	return i
+}
+
+// Rebuild regenerates the indicator image for the new dimensions in cfg, e.g. when the screen resolution changes.
+// Like GetImage, it must not be called concurrently with itself or GetImage.
+func (i *Indicator) Rebuild(cfg *Config) {
+	margin := int(math.Ceil(cfg.TipRadius + cfg.LineWidth*2))
+	size := 2 * (cfg.Radius + margin)
+
+	if i.finalImg != nil {
+		i.finalImg.Dispose()
+	}
+
+	i.finalImg = ebiten.NewImage(size, size)
+	i.cfg = *cfg
+	i.center = float32(size) / 2
+
+	forward, right := i.GetVelocity()
+	i.redrawFinalImage(i.tip(forward, right))
+}
+
+type Indicator struct {
+	rwMutex sync.RWMutex
+
+	// images
+	finalImg *ebiten.Image
+
+	// image transformation variables
+	cfg    Config
+	center float32
+
+	// drawn state
+	drawnTip image.Point
+
+	// thread-safe
+	forward float64
+	right   float64
+}
+
+// SetVelocity sets the ground velocity in m/s in the aircraft heading frame.
+func (i *Indicator) SetVelocity(forward, right float64) {
+	m := &i.rwMutex
+	m.Lock()
+	i.forward = forward
+	i.right = right
+	m.Unlock()
+}
+
+func (i *Indicator) GetVelocity() (forward, right float64) {
+	m := &i.rwMutex
+	m.RLock()
+	forward = i.forward
+	right = i.right
+	m.RUnlock()
+
+	return
+}
+
+func (i *Indicator) GetImage() (img *ebiten.Image, isRedrawn bool) {
+	// optimization: redraw the final image only if the tip has moved to another pixel
+	if tip := i.tip(i.GetVelocity()); tip != i.drawnTip {
+		i.redrawFinalImage(tip)
+
+		isRedrawn = true
+	}
+
+	img = i.finalImg
+
+	return
+}
+
+// tip returns the offset of the vector tip from the center in pixels, the vector is clipped to the radius.
+func (i *Indicator) tip(forward, right float64) image.Point {
+	radius := float64(i.cfg.Radius)
+	x := right * radius / i.cfg.MaxSpeed
+	y := -forward * radius / i.cfg.MaxSpeed
+
+	if length := math.Hypot(x, y); length > radius {
+		x *= radius / length
+		y *= radius / length
+	}
+
+	return image.Pt(int(math.Round(x)), int(math.Round(y)))
+}
+
+func (i *Indicator) redrawFinalImage(tip image.Point) {
+	finalImg := i.finalImg
+	finalImg.Clear()
+
+	cfg := &i.cfg
+	c := i.center
+	cross := float32(cfg.CrossSize)
+	tipX := c + float32(tip.X)
+	tipY := c + float32(tip.Y)
+	tipRadius := float32(cfg.TipRadius)
+
+	// the border is drawn under the lines, so that the symbol is visible on the bright background
+	for _, stroke := range []struct {
+		width float32
+		color color.NRGBA
+	}{
+		{float32(cfg.LineWidth * 3), cfg.BorderColor},
+		{float32(cfg.LineWidth), cfg.Color},
+	} {
+		// aircraft reference cross
+		vector.StrokeLine(finalImg, c-cross, c, c+cross, c, stroke.width, stroke.color, true)
+		vector.StrokeLine(finalImg, c, c-cross, c, c+cross, stroke.width, stroke.color, true)
+
+		// the vector is not drawn in the hover without drift
+		if tip != (image.Point{}) {
+			vector.StrokeLine(finalImg, c, c, tipX, tipY, stroke.width, stroke.color, true)
+			vector.StrokeCircle(finalImg, tipX, tipY, tipRadius, stroke.width, stroke.color, true)
+		}
+	}
+
+	// update the tip for which the final image is rendered
+	i.drawnTip = tip
+}
@@ -0,0 +1,34 @@
+package dcshmd
+
+import (
+	"github.com/dimchansky/dcs-hmd/utils"
+)
+
+// Windows virtual-key codes of the hotkeys.
+const (
+	vkF10      = 0x79
+	vkRShift   = 0xA1
+	vkRControl = 0xA3
+)
+
+// hotkey is the combination of the keys that are held down together.
+type hotkey struct {
+	keys []int
+	down bool
+}
+
+// JustPressed reports whether the combination has been pressed since the previous call.
+func (k *hotkey) JustPressed() bool {
+	down := true
+	for _, vk := range k.keys {
+		if !utils.IsKeyDown(vk) {
+			down = false
+			break
+		}
+	}
+
+	pressed := down && !k.down
+	k.down = down
+
+	return pressed
+}
@@ -0,0 +1,39 @@
+package dcshmd
+
+// hoverHotkeys are the global hotkeys of the hover display, so that they work while DCS has the focus.
+type hoverHotkeys struct {
+	// toggle shows or hides the hover display in place of the ground velocity vector.
+	toggle hotkey
+	// target sets the target hover point at the current position and shows the hover display.
+	target hotkey
+	// clear removes the target hover point.
+	clear hotkey
+}
+
+func newHoverHotkeys() hoverHotkeys {
+	return hoverHotkeys{
+		toggle: hotkey{keys: []int{vkRControl, vkRShift, 'H'}},
+		target: hotkey{keys: []int{vkRControl, vkRShift, 'T'}},
+		clear:  hotkey{keys: []int{vkRControl, vkRShift, 'C'}},
+	}
+}
+
+// updateHover handles the hover display hotkeys.
+func (h *HUD) updateHover() {
+	keys := &h.hoverKeys
+
+	toggle := keys.toggle.JustPressed()
+	if keys.target.JustPressed() && h.hover.SetTarget() && !h.hoverShown {
+		toggle = true
+	}
+
+	if keys.clear.JustPressed() {
+		h.hover.ClearTarget()
+	}
+
+	if toggle {
+		h.hoverShown = !h.hoverShown
+		// the hover display takes the place of the velocity vector, so the screen must be cleared
+		h.invalidate()
+	}
+}
@@ -4,23 +4,31 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"math"
 	"sync"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 
 	"github.com/dimchansky/dcs-hmd/aircraft/ka-50/devices/rotorpitch"
 	"github.com/dimchansky/dcs-hmd/aircraft/ka-50/devices/rotorrpm"
 	"github.com/dimchansky/dcs-hmd/aircraft/ka-50/devices/verticalvelocity"
+	"github.com/dimchansky/dcs-hmd/aircraft/ka-50/outputparser"
+	"github.com/dimchansky/dcs-hmd/diagnostics"
+	"github.com/dimchansky/dcs-hmd/gui/gmeter"
+	"github.com/dimchansky/dcs-hmd/gui/hover"
+	"github.com/dimchansky/dcs-hmd/gui/slipindicator"
+	"github.com/dimchansky/dcs-hmd/gui/velocityvector"
+	"github.com/dimchansky/dcs-hmd/metrics"
+	"github.com/dimchansky/dcs-hmd/settings"
 	"github.com/dimchansky/dcs-hmd/utils"
 )
 
 const (
+	// ScreenWidth and ScreenHeight define the reference screen size, all HUD dimensions are given for it.
 	ScreenWidth  = 800
 	ScreenHeight = 600
 
-	fontBaseSize = 18
-	dpi          = 72
-
 	rowWidth  = 20
 	rowHeight = 20
 )
@@ -30,7 +38,26 @@ var (
 	shadowColor = color.NRGBA{A: 0xff}
 )
 
-func NewHUD() (*HUD, error) {
+// HUDConfig holds the HUD options.
+type HUDConfig struct {
+	Font   FontConfig
+	Window settings.Window
+	// Diagnostics shows the diagnostics overlay on start, it is toggled by RCtrl+RShift+F10 keys.
+	Diagnostics bool
+	// Hover shows the hover display on start instead of the ground velocity vector, it is toggled by
+	// RCtrl+RShift+H keys.
+	Hover bool
+}
+
+// DefaultHUDConfig returns the default HUD options.
+func DefaultHUDConfig() HUDConfig {
+	return HUDConfig{
+		Font:   DefaultFontConfig(),
+		Window: settings.Default().Window,
+	}
+}
+
+func NewHUD(cfg *HUDConfig) (*HUD, error) {
 	ebiten.SetScreenClearedEveryFrame(false)
 	ebiten.SetScreenFilterEnabled(false)
 
@@ -39,64 +66,34 @@ func NewHUD() (*HUD, error) {
 	ebiten.SetScreenTransparent(true)
 	ebiten.SetWindowDecorated(false)
 	ebiten.SetWindowFloating(true)
-	ebiten.SetWindowSize(ScreenWidth, ScreenHeight)
-
-	const (
-		indicatorHeight = 400
-		xSpan           = rowWidth / 2
-		ySpan           = rowHeight
-	)
-
-	rotorPitchIndicator := rotorpitch.NewIndicator(&rotorpitch.IndicatorConfig{
-		Width:           rowWidth * 3,
-		Height:          indicatorHeight,
-		TickLength:      rowWidth,
-		MinorTickLength: rowWidth / 2,
-		LineWidth:       2,
-		Color:           textColor,
-		BorderColor:     shadowColor,
-		Rect: image.Rectangle{
-			Min: image.Pt(xSpan, ySpan),
-			Max: image.Pt(rowWidth*2+xSpan, indicatorHeight-ySpan),
-		},
-	})
-	rotorRPMIndicator := rotorrpm.NewIndicator(&rotorrpm.IndicatorConfig{
-		Width:           rowWidth * 3,
-		Height:          indicatorHeight,
-		TickLength:      rowWidth,
-		MinorTickLength: rowWidth * 3 / 4,
-		LineWidth:       2,
-		Color:           textColor,
-		BorderColor:     shadowColor,
-		Rect: image.Rectangle{
-			Min: image.Pt(xSpan, ySpan),
-			Max: image.Pt(rowWidth*2+xSpan, indicatorHeight-ySpan),
-		},
-	})
-	verticalVelocityIndicator := verticalvelocity.NewIndicator(&verticalvelocity.IndicatorConfig{
-		Width:           rowWidth * 3,
-		Height:          indicatorHeight,
-		TickLength:      rowWidth,
-		MinorTickLength: rowWidth * 3 / 4,
-		LineWidth:       2,
-		Color:           textColor,
-		BorderColor:     shadowColor,
-		Rect: image.Rectangle{
-			Min: image.Pt(xSpan, ySpan),
-			Max: image.Pt(rowWidth*2+xSpan, indicatorHeight-ySpan),
-		},
-	})
-
-	ff, err := NewFontFace(fontBaseSize, dpi)
+
+	if err := applyWindowSettings(&cfg.Window); err != nil {
+		return nil, err
+	}
+
+	layout := newHUDLayout(image.Pt(ScreenWidth, ScreenHeight))
+
+	ff, err := NewFontFace(layout.fontConfig(&cfg.Font))
 	if err != nil {
 		return nil, err
 	}
 
 	hud := &HUD{
+		cfg:                       *cfg,
+		layout:                    layout,
+		screenSize:                layout.screenSize,
 		fontFace:                  ff,
-		rotorPitchIndicator:       rotorPitchIndicator,
-		rotorRPMIndicator:         rotorRPMIndicator,
-		verticalVelocityIndicator: verticalVelocityIndicator,
+		rotorPitchIndicator:       rotorpitch.NewIndicator(layout.rotorPitchConfig(ff.Face)),
+		rotorRPMIndicator:         rotorrpm.NewIndicator(layout.rotorRPMConfig(ff.Face)),
+		verticalVelocityIndicator: verticalvelocity.NewIndicator(layout.verticalVelocityConfig(ff.Face)),
+		gMeter:                    gmeter.New(layout.gMeterConfig(ff.Face)),
+		slipIndicator:             slipindicator.New(layout.slipIndicatorConfig()),
+		velocityVector:            velocityvector.New(layout.velocityVectorConfig()),
+		hover:                     hover.New(layout.hoverConfig()),
+		hoverShown:                cfg.Hover,
+		hoverKeys:                 newHoverHotkeys(),
+		overlayKey:                newOverlayHotkey(),
+		overlay:                   overlay{shown: cfg.Diagnostics},
 	}
 
 	return hud, nil
@@ -105,14 +102,68 @@ func NewHUD() (*HUD, error) {
 type HUD struct {
 	once sync.Once
 
+	cfg HUDConfig
+
+	// layout is the layout the HUD images are built for, screenSize is the latest screen size reported by Layout
+	layout      hudLayout
+	screenSize  image.Point
+	clearScreen bool
+
 	fontFace                  *FontFace
 	rotorPitchIndicator       *rotorpitch.Indicator
 	rotorRPMIndicator         *rotorrpm.Indicator
 	verticalVelocityIndicator *verticalvelocity.Indicator
+	gMeter                    *gmeter.Indicator
+	slipIndicator             *slipindicator.Indicator
+	velocityVector            *velocityvector.Indicator
+	hover                     *hover.Indicator
 
 	rotorPitchImg       redrawnImage
 	rotorRPMImg         redrawnImage
 	verticalVelocityImg redrawnImage
+	gMeterImg           redrawnImage
+	slipIndicatorImg    redrawnImage
+	velocityVectorImg   redrawnImage
+	hoverImg            redrawnImage
+
+	// hoverShown shows the hover display in place of the velocity vector
+	hoverShown bool
+	hoverKeys  hoverHotkeys
+
+	diagnostics *diagnostics.Diagnostics
+	overlay     overlay
+	overlayKey  hotkey
+	linkWarning linkWarning
+
+	metrics *metrics.HUD
+}
+
+// Names of the indicators in the metrics.
+const (
+	rotorPitchName       = "rotor_pitch"
+	rotorRPMName         = "rotor_rpm"
+	verticalVelocityName = "vertical_velocity"
+	gMeterName           = "g_meter"
+	slipIndicatorName    = "slip_indicator"
+	velocityVectorName   = "velocity_vector"
+	hoverName            = "hover"
+)
+
+// NewHUDMetrics creates the rendering metrics for all the HUD indicators.
+func NewHUDMetrics() *metrics.HUD {
+	return metrics.NewHUD(rotorPitchName, rotorRPMName, verticalVelocityName,
+		gMeterName, slipIndicatorName, velocityVectorName, hoverName)
+}
+
+// SetMetrics sets the rendering metrics the HUD reports to, it must be called before the game is run.
+func (h *HUD) SetMetrics(m *metrics.HUD) {
+	h.metrics = m
+}
+
+// SetDiagnostics sets the source of the diagnostics overlay and the link warning,
+// it must be called before the game is run.
+func (h *HUD) SetDiagnostics(d *diagnostics.Diagnostics) {
+	h.diagnostics = d
 }
 
 func (h *HUD) Close() error {
@@ -122,38 +173,175 @@ func (h *HUD) Close() error {
 func (h *HUD) Update() error {
 	h.once.Do(enableCurrentProcessWindowClickThroughAsync)
 
+	if m := h.metrics; m != nil {
+		start := time.Now()
+		defer func() { m.ObserveUpdate(time.Since(start)) }()
+	}
+
+	if h.screenSize != h.layout.screenSize {
+		if err := h.rebuild(newHUDLayout(h.screenSize)); err != nil {
+			return err
+		}
+	}
+
+	if h.overlayKey.JustPressed() {
+		h.overlay.shown = !h.overlay.shown
+		if !h.overlay.shown {
+			h.invalidate()
+		}
+	}
+
+	h.updateHover()
+
+	now := time.Now()
+
+	degraded := h.diagnostics != nil && h.diagnostics.LinkDegraded(now)
+	if h.linkWarning.Update(degraded, h.fontFace) {
+		h.invalidate()
+	}
+
+	h.overlay.Update(now, h.diagnostics, &h.layout, h.fontFace)
+
 	h.rotorPitchImg.Update(h.rotorPitchIndicator.GetImage())
 	h.rotorRPMImg.Update(h.rotorRPMIndicator.GetImage())
 	h.verticalVelocityImg.Update(h.verticalVelocityIndicator.GetImage())
+	h.gMeterImg.Update(h.gMeter.GetImage())
+	h.slipIndicatorImg.Update(h.slipIndicator.GetImage())
+	if h.hoverShown {
+		h.hoverImg.Update(h.hover.GetImage())
+	} else {
+		h.velocityVectorImg.Update(h.velocityVector.GetImage())
+	}
 
 	return nil
 }
 
+// rebuild regenerates the font face and all indicator images for the new layout.
+func (h *HUD) rebuild(layout hudLayout) error {
+	ff, err := NewFontFace(layout.fontConfig(&h.cfg.Font))
+	if err != nil {
+		return err
+	}
+
+	h.rotorPitchIndicator.Rebuild(layout.rotorPitchConfig(ff.Face))
+	h.rotorRPMIndicator.Rebuild(layout.rotorRPMConfig(ff.Face))
+	h.verticalVelocityIndicator.Rebuild(layout.verticalVelocityConfig(ff.Face))
+	h.gMeter.Rebuild(layout.gMeterConfig(ff.Face))
+	h.slipIndicator.Rebuild(layout.slipIndicatorConfig())
+	h.velocityVector.Rebuild(layout.velocityVectorConfig())
+	h.hover.Rebuild(layout.hoverConfig())
+
+	_ = h.fontFace.Close()
+	h.fontFace = ff
+	h.layout = layout
+
+	// positions of the images have changed, so the whole screen must be redrawn
+	h.invalidate()
+
+	return nil
+}
+
+// invalidate clears the screen and draws all the images again in the next frame.
+func (h *HUD) invalidate() {
+	h.rotorPitchImg = redrawnImage{}
+	h.rotorRPMImg = redrawnImage{}
+	h.verticalVelocityImg = redrawnImage{}
+	h.gMeterImg = redrawnImage{}
+	h.slipIndicatorImg = redrawnImage{}
+	h.velocityVectorImg = redrawnImage{}
+	h.hoverImg = redrawnImage{}
+	h.overlay.Reset()
+	h.linkWarning.Reset()
+	h.clearScreen = true
+}
+
 func (h *HUD) Draw(screen *ebiten.Image) {
+	if m := h.metrics; m != nil {
+		start := time.Now()
+		defer func() { m.ObserveDraw(time.Since(start)) }()
+	}
+
 	rotorPitchImg := &h.rotorPitchImg
 	rotorRPMImg := &h.rotorRPMImg
 	verticalVelocityImg := &h.verticalVelocityImg
+	gMeterImg := &h.gMeterImg
+	slipIndicatorImg := &h.slipIndicatorImg
+	velocityVectorImg := &h.velocityVectorImg
+	hoverImg := &h.hoverImg
+
+	if h.clearScreen {
+		screen.Clear()
+		h.clearScreen = false
+	}
+
+	h.overlay.DrawOn(screen, &h.layout)
+	h.linkWarning.DrawOn(screen, &h.layout)
 
 	if !rotorPitchImg.NeedToDraw &&
 		!rotorRPMImg.NeedToDraw &&
-		!verticalVelocityImg.NeedToDraw {
+		!verticalVelocityImg.NeedToDraw &&
+		!gMeterImg.NeedToDraw &&
+		!slipIndicatorImg.NeedToDraw &&
+		!velocityVectorImg.NeedToDraw &&
+		!hoverImg.NeedToDraw {
 		return
 	}
 
+	layout := &h.layout
+
 	op := &ebiten.DrawImageOptions{CompositeMode: ebiten.CompositeModeCopy}
-	op.GeoM.Translate(0, rowHeight)
-	rotorPitchImg.DrawOn(screen, op)
+	op.GeoM.Translate(0, layout.indicatorsTop())
+	h.redrawn(rotorPitchName, rotorPitchImg.DrawOn(screen, op))
 
 	op.GeoM.Translate(float64(rotorPitchImg.Size().X), 0)
-	rotorRPMImg.DrawOn(screen, op)
+	h.redrawn(rotorRPMName, rotorRPMImg.DrawOn(screen, op))
 
 	op.GeoM.Reset()
-	op.GeoM.Translate(ScreenWidth-float64(verticalVelocityImg.Size().X)-1, rowHeight)
-	verticalVelocityImg.DrawOn(screen, op)
+	op.GeoM.Translate(float64(layout.screenSize.X-verticalVelocityImg.Size().X-1), layout.indicatorsTop())
+	h.redrawn(verticalVelocityName, verticalVelocityImg.DrawOn(screen, op))
+
+	// the flight symbology is drawn around the screen center, the hover display takes the place
+	// of the velocity vector
+	centerName, centerImg := velocityVectorName, velocityVectorImg
+	if h.hoverShown {
+		centerName, centerImg = hoverName, hoverImg
+	}
+
+	for _, s := range []struct {
+		name string
+		img  *redrawnImage
+		pos  func(size image.Point) image.Point
+	}{
+		{centerName, centerImg, layout.velocityVectorPos},
+		{gMeterName, gMeterImg, layout.gMeterPos},
+		{slipIndicatorName, slipIndicatorImg, layout.slipIndicatorPos},
+	} {
+		pos := s.pos(s.img.Size())
+		op.GeoM.Reset()
+		op.GeoM.Translate(float64(pos.X), float64(pos.Y))
+		h.redrawn(s.name, s.img.DrawOn(screen, op))
+	}
+}
+
+// redrawn counts the redraw of the indicator in the metrics if the image has been drawn.
+func (h *HUD) redrawn(name string, drawn bool) {
+	if drawn && h.metrics != nil {
+		h.metrics.Redrawn(name)
+	}
 }
 
+// Layout returns the screen size in native pixels, so that the HUD is rendered at the monitor resolution
+// regardless of the device scale factor.
 func (h *HUD) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
-	return ScreenWidth, ScreenHeight
+	scaleFactor := ebiten.DeviceScaleFactor()
+	screenWidth = int(math.Ceil(float64(outsideWidth) * scaleFactor))
+	screenHeight = int(math.Ceil(float64(outsideHeight) * scaleFactor))
+
+	if screenWidth > 0 && screenHeight > 0 {
+		h.screenSize = image.Pt(screenWidth, screenHeight)
+	}
+
+	return h.screenSize.X, h.screenSize.Y
 }
 
 // SetRotorPitch is thread-safe to update rotor pitch.
@@ -171,6 +359,15 @@ func (h *HUD) SetVerticalVelocity(val float64) {
 	h.verticalVelocityIndicator.SetVerticalVelocity(val)
 }
 
+// SetFlightData is thread-safe to update the flight symbology: the G meter, the slip indicator, the ground
+// velocity vector and the hover display.
+func (h *HUD) SetFlightData(fd outputparser.FlightData) {
+	h.gMeter.SetG(fd.Acceleration.Y)
+	h.slipIndicator.SetSlip(fd.SlipBall)
+	h.velocityVector.SetVelocity(fd.GroundVelocity())
+	h.hover.SetFlightData(fd)
+}
+
 func enableCurrentProcessWindowClickThroughAsync() {
 	go utils.EnableCurrentProcessWindowClickThrough()
 }
@@ -192,12 +389,16 @@ func (i *redrawnImage) Update(img *ebiten.Image, isRedrawn bool) {
 }
 
 // DrawOn draws the image on the given screen with the given options if the NeedToDraw flag is true.
-// After drawing, NeedToDraw is set to false.
-func (i *redrawnImage) DrawOn(screen *ebiten.Image, op *ebiten.DrawImageOptions) {
-	if i.NeedToDraw {
-		screen.DrawImage(i.img, op)
-		i.NeedToDraw = false
+// After drawing, NeedToDraw is set to false. It reports whether the image has been drawn.
+func (i *redrawnImage) DrawOn(screen *ebiten.Image, op *ebiten.DrawImageOptions) bool {
+	if !i.NeedToDraw {
+		return false
 	}
+
+	screen.DrawImage(i.img, op)
+	i.NeedToDraw = false
+
+	return true
 }
 
 // Size returns the size of the image.
@@ -0,0 +1,127 @@
+package installer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+)
+
+// FileStatus is the state of the installed file compared to the file that should be installed.
+type FileStatus int
+
+const (
+	// FileMatches means that the installed file is the same byte for byte.
+	FileMatches FileStatus = iota
+	// FileModified means that the installed file differs.
+	FileModified
+	// FileMissing means that the file is not installed.
+	FileMissing
+	// FileUnknown means that the file is not the one that is installed, e.g. the file of the previous version.
+	FileUnknown
+)
+
+func (s FileStatus) String() string {
+	switch s {
+	case FileMatches:
+		return "matches"
+	case FileModified:
+		return "modified"
+	case FileMissing:
+		return "missing"
+	case FileUnknown:
+		return "unknown"
+	default:
+		return fmt.Sprintf("FileStatus(%d)", int(s))
+	}
+}
+
+// FileCheck is the status of the installed file.
+type FileCheck struct {
+	// Path is the slash-separated path relative to the scripts directory.
+	Path   string
+	Status FileStatus
+}
+
+// CompareFiles compares the files of the installed fsys with the ones of want. The files in the directories of want,
+// including the root one, that are not in it are reported as unknown, except the generated ones.
+// The checks are sorted by path.
+func CompareFiles(want, installed fs.FS, generated ...string) ([]FileCheck, error) {
+	var checks []FileCheck
+
+	err := fs.WalkDir(want, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return findUnknown(want, installed, path, generated, &checks)
+		}
+
+		status, err := compareFile(want, installed, path)
+		if err != nil {
+			return err
+		}
+
+		checks = append(checks, FileCheck{Path: path, Status: status})
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(checks, func(a, b FileCheck) int { return strings.Compare(a.Path, b.Path) })
+
+	return checks, nil
+}
+
+func compareFile(want, installed fs.FS, path string) (FileStatus, error) {
+	wantData, err := fs.ReadFile(want, path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read '%s' file: %w", path, err)
+	}
+
+	data, err := fs.ReadFile(installed, path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return FileMissing, nil
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to read installed '%s' file: %w", path, err)
+	}
+
+	if !bytes.Equal(wantData, data) {
+		return FileModified, nil
+	}
+
+	return FileMatches, nil
+}
+
+// findUnknown appends the checks of the installed files in the directory that are not in want.
+func findUnknown(want, installed fs.FS, dir string, generated []string, checks *[]FileCheck) error {
+	entries, err := fs.ReadDir(installed, dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to read installed '%s' directory: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		path := path.Join(dir, entry.Name())
+		if slices.Contains(generated, path) {
+			continue
+		}
+
+		if _, err := fs.Stat(want, path); errors.Is(err, fs.ErrNotExist) {
+			*checks = append(*checks, FileCheck{Path: path, Status: FileUnknown})
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package installer_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimchansky/dcs-hmd/installer"
+)
+
+func TestCompareFiles(t *testing.T) {
+	want := fstest.MapFS{
+		"Core.lua":        {Data: []byte("core")},
+		"Export.lua":      {Data: []byte("export")},
+		"Udp.lua":         {Data: []byte("udp")},
+		"Aircraft/Ka.lua": {Data: []byte("ka")},
+	}
+
+	installed := fstest.MapFS{
+		"Core.lua":         {Data: []byte("core")},
+		"Export.lua":       {Data: []byte("export\r\n")},
+		"Config.lua":       {Data: []byte("config")},
+		"Old.lua":          {Data: []byte("old")},
+		"OldDir/Old.lua":   {Data: []byte("old")},
+		"Aircraft/Ka.lua":  {Data: []byte("ka")},
+		"Aircraft/Old.lua": {Data: []byte("old")},
+	}
+
+	checks, err := installer.CompareFiles(want, installed, "Config.lua")
+	require.NoError(t, err)
+	require.Equal(t, []installer.FileCheck{
+		{Path: "Aircraft/Ka.lua", Status: installer.FileMatches},
+		{Path: "Aircraft/Old.lua", Status: installer.FileUnknown},
+		{Path: "Core.lua", Status: installer.FileMatches},
+		{Path: "Export.lua", Status: installer.FileModified},
+		{Path: "Old.lua", Status: installer.FileUnknown},
+		{Path: "OldDir", Status: installer.FileUnknown},
+		{Path: "Udp.lua", Status: installer.FileMissing},
+	}, checks)
+
+	checks, err = installer.CompareFiles(want, fstest.MapFS{})
+	require.NoError(t, err)
+	require.Len(t, checks, 4)
+
+	for _, c := range checks {
+		require.Equal(t, installer.FileMissing, c.Status, c.Path)
+	}
+}
@@ -0,0 +1,159 @@
+package installer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of the unchanged lines around the changes in the unified diff.
+const diffContext = 3
+
+// edit is the line of the diff: the unchanged line, the deleted one or the inserted one.
+type edit struct {
+	op   byte // ' ', '-' or '+'
+	line string
+}
+
+// UnifiedDiff returns the unified diff of the texts, it is empty if they are equal.
+// The scripts are small, so the longest common subsequence of the lines is found in quadratic time.
+func UnifiedDiff(oldName, newName string, old, new []byte) string {
+	edits := diffLines(splitLines(string(old)), splitLines(string(new)))
+
+	var b strings.Builder
+
+	for start := 0; start < len(edits); {
+		first := nextChange(edits, start)
+		if first < 0 {
+			break
+		}
+
+		if b.Len() == 0 {
+			fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+		}
+
+		// the hunk continues while the next change is close enough for the contexts to overlap
+		last := first
+		for next := nextChange(edits, last+1); next >= 0 && next-last <= 2*diffContext; next = nextChange(edits, last+1) {
+			last = next
+		}
+
+		from := max(first-diffContext, 0)
+		to := min(last+diffContext+1, len(edits))
+
+		writeHunk(&b, edits, from, to)
+
+		start = to
+	}
+
+	return b.String()
+}
+
+// diffLines returns the edits that turn the old lines into the new ones.
+func diffLines(a, b []string) []edit {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	edits := make([]edit, 0, len(a)+len(b))
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i]})
+			i++
+			j++
+		case j == len(b) || i < len(a) && lcs[i+1][j] >= lcs[i][j+1]:
+			edits = append(edits, edit{'-', a[i]})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j]})
+			j++
+		}
+	}
+
+	return edits
+}
+
+// nextChange returns the index of the first changed line starting from the index or -1 if there is none.
+func nextChange(edits []edit, from int) int {
+	for i := from; i < len(edits); i++ {
+		if edits[i].op != ' ' {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// writeHunk writes the hunk of the edits with the header of the line ranges.
+func writeHunk(b *strings.Builder, edits []edit, from, to int) {
+	oldStart, newStart := 1, 1
+
+	for _, e := range edits[:from] {
+		if e.op != '+' {
+			oldStart++
+		}
+
+		if e.op != '-' {
+			newStart++
+		}
+	}
+
+	oldCount, newCount := 0, 0
+
+	for _, e := range edits[from:to] {
+		if e.op != '+' {
+			oldCount++
+		}
+
+		if e.op != '-' {
+			newCount++
+		}
+	}
+
+	fmt.Fprintf(b, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+
+	for _, e := range edits[from:to] {
+		b.WriteByte(e.op)
+		b.WriteString(e.line)
+
+		if !strings.HasSuffix(e.line, "\n") {
+			b.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange formats the line range of the hunk, the empty range starts at the line before it.
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprint(start)
+	default:
+		return fmt.Sprintf("%d,%d", start, count)
+	}
+}
+
+// splitLines splits the text into the lines with their line endings.
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
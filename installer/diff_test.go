@@ -0,0 +1,63 @@
+package installer_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimchansky/dcs-hmd/installer"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{
+			name: "equal",
+			old:  "a\nb\n",
+			new:  "a\nb\n",
+		},
+		{
+			name: "prepend line",
+			old:  "a\nb\nc\nd\ne\n",
+			new:  "hook\na\nb\nc\nd\ne\n",
+			want: "--- a/Export.lua\n+++ b/Export.lua\n@@ -1,3 +1,4 @@\n+hook\n a\n b\n c\n",
+		},
+		{
+			name: "new file",
+			new:  "hook\n",
+			want: "--- a/Export.lua\n+++ b/Export.lua\n@@ -0,0 +1 @@\n+hook\n",
+		},
+		{
+			name: "remove line without new line at end of file",
+			old:  "a\nhook",
+			new:  "a\n",
+			want: "--- a/Export.lua\n+++ b/Export.lua\n@@ -1,2 +1 @@\n a\n-hook\n\\ No newline at end of file\n",
+		},
+		{
+			name: "separate hunks",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			new:  "0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			want: "--- a/Export.lua\n+++ b/Export.lua\n" +
+				"@@ -1,3 +1,4 @@\n+0\n 1\n 2\n 3\n" +
+				"@@ -7,4 +8,3 @@\n 7\n 8\n 9\n-10\n",
+		},
+		{
+			name: "merged hunks",
+			old:  "1\n2\n3\n4\n5\n6\n7\n",
+			new:  "1\nx\n3\n4\n5\n6\ny\n",
+			want: "--- a/Export.lua\n+++ b/Export.lua\n" +
+				"@@ -1,7 +1,7 @@\n 1\n-2\n+x\n 3\n 4\n 5\n 6\n-7\n+y\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got := installer.UnifiedDiff("a/Export.lua", "b/Export.lua", []byte(tt.old), []byte(tt.new))
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package installer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// SavedGamesDirName is the name of the Saved Games folder in the user home directory.
+	SavedGamesDirName = "Saved Games"
+
+	// ScriptsDirName is the name of the scripts directory in the DCS write directory.
+	ScriptsDirName = "Scripts"
+)
+
+// KnownFolders locates the Saved Games folder that may be redirected from the home directory, e.g. to another drive.
+type KnownFolders interface {
+	// SavedGames returns the path of the Saved Games folder.
+	SavedGames() (string, error)
+}
+
+// DCSDir is the DCS write directory found in the Saved Games folder.
+type DCSDir struct {
+	// Variant is the name of the directory, e.g. "DCS", "DCS.openbeta" or "DCS.server".
+	Variant string
+	// Path is the path of the DCS write directory.
+	Path string
+	// ScriptsDir is the path of the scripts directory in it.
+	ScriptsDir string
+	// HasScripts reports whether the scripts directory exists.
+	HasScripts bool
+}
+
+// Discover finds the DCS write directories in the Saved Games folder of the home directory and in the one
+// located by knownFolders, which may be nil. The directories are sorted by path.
+func Discover(home string, knownFolders KnownFolders) ([]DCSDir, error) {
+	savedGamesDirs := []string{filepath.Join(home, SavedGamesDirName)}
+
+	if knownFolders != nil {
+		if dir, err := knownFolders.SavedGames(); err != nil {
+			slog.Warn("failed to locate Saved Games folder", "error", err)
+		} else if dir != "" {
+			savedGamesDirs = append(savedGamesDirs, dir)
+		}
+	}
+
+	var dirs []DCSDir
+
+	for i, savedGamesDir := range savedGamesDirs {
+		if isListed(savedGamesDirs[:i], savedGamesDir) {
+			continue
+		}
+
+		found, err := discoverIn(savedGamesDir)
+		if err != nil {
+			return nil, err
+		}
+
+		dirs = append(dirs, found...)
+	}
+
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].Path < dirs[j].Path })
+
+	return dirs, nil
+}
+
+// discoverIn finds the DCS write directories in the Saved Games folder, it finds none if the folder doesn't exist.
+func discoverIn(savedGamesDir string) ([]DCSDir, error) {
+	entries, err := os.ReadDir(savedGamesDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Saved Games folder '%s': %w", savedGamesDir, err)
+	}
+
+	var dirs []DCSDir
+
+	for _, entry := range entries {
+		if !entry.IsDir() || !isDCSVariant(entry.Name()) {
+			continue
+		}
+
+		dir := DCSDir{
+			Variant:    entry.Name(),
+			Path:       filepath.Join(savedGamesDir, entry.Name()),
+			ScriptsDir: filepath.Join(savedGamesDir, entry.Name(), ScriptsDirName),
+		}
+
+		if info, err := os.Stat(dir.ScriptsDir); err == nil && info.IsDir() {
+			dir.HasScripts = true
+		}
+
+		dirs = append(dirs, dir)
+	}
+
+	return dirs, nil
+}
+
+// isDCSVariant reports whether the directory name is the one of the DCS write directories:
+// "DCS" of the stable release and "DCS.<variant>" of the others, e.g. "DCS.openbeta" or "DCS.server".
+func isDCSVariant(name string) bool {
+	name = strings.ToLower(name)
+
+	return name == "dcs" || strings.HasPrefix(name, "dcs.") && len(name) > len("dcs.")
+}
+
+// isListed reports whether the directory is in the list, the paths are compared case-insensitively
+// as Windows does.
+func isListed(dirs []string, dir string) bool {
+	for _, d := range dirs {
+		if strings.EqualFold(filepath.Clean(d), filepath.Clean(dir)) {
+			return true
+		}
+	}
+
+	return false
+}
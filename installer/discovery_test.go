@@ -0,0 +1,76 @@
+package installer_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimchansky/dcs-hmd/installer"
+)
+
+type knownFolders struct {
+	savedGames string
+	err        error
+}
+
+func (f knownFolders) SavedGames() (string, error) { return f.savedGames, f.err }
+
+func TestDiscover(t *testing.T) {
+	home := t.TempDir()
+	savedGames := filepath.Join(home, installer.SavedGamesDirName)
+	mkdirs(t, savedGames, "DCS", "DCS.openbeta/Scripts", "DCS.server", "DCS.", "Other/Scripts")
+	writeFiles(t, savedGames, map[string]string{"DCS.file": ""})
+
+	redirected := filepath.Join(t.TempDir(), "Games")
+	mkdirs(t, redirected, "DCS.openbeta_server/Scripts")
+
+	homeDirs := []installer.DCSDir{
+		{Variant: "DCS", Path: filepath.Join(savedGames, "DCS"), ScriptsDir: filepath.Join(savedGames, "DCS", "Scripts")},
+		{Variant: "DCS.openbeta", Path: filepath.Join(savedGames, "DCS.openbeta"), ScriptsDir: filepath.Join(savedGames, "DCS.openbeta", "Scripts"), HasScripts: true},
+		{Variant: "DCS.server", Path: filepath.Join(savedGames, "DCS.server"), ScriptsDir: filepath.Join(savedGames, "DCS.server", "Scripts")},
+	}
+	redirectedDirs := []installer.DCSDir{
+		{Variant: "DCS.openbeta_server", Path: filepath.Join(redirected, "DCS.openbeta_server"), ScriptsDir: filepath.Join(redirected, "DCS.openbeta_server", "Scripts"), HasScripts: true},
+	}
+
+	tests := []struct {
+		name         string
+		home         string
+		knownFolders installer.KnownFolders
+		want         []installer.DCSDir
+	}{
+		{"home only", home, nil, homeDirs},
+		{"redirected Saved Games", home, knownFolders{savedGames: redirected}, sorted(append(homeDirs, redirectedDirs...))},
+		{"Saved Games in home", home, knownFolders{savedGames: savedGames}, homeDirs},
+		{"known folders error", home, knownFolders{err: errors.New("not found")}, homeDirs},
+		{"no Saved Games in home", t.TempDir(), knownFolders{savedGames: redirected}, redirectedDirs},
+		{"nothing found", t.TempDir(), nil, nil},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			dirs, err := installer.Discover(tt.home, tt.knownFolders)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, dirs)
+		})
+	}
+}
+
+func mkdirs(t *testing.T, dir string, names ...string) {
+	t.Helper()
+
+	for _, name := range names {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.FromSlash(name)), 0755))
+	}
+}
+
+func sorted(dirs []installer.DCSDir) []installer.DCSDir {
+	slices.SortFunc(dirs, func(a, b installer.DCSDir) int { return strings.Compare(a.Path, b.Path) })
+	return dirs
+}
@@ -0,0 +1,501 @@
+// Package installer checks and changes the DCS scripts directory the exporter is installed to.
+package installer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Hook is the line of Export.lua that loads the exporter.
+type Hook struct {
+	// Line is the line the installer adds to Export.lua.
+	Line string
+	// Script is the path of the script loaded by the line, relative to the DCS write directory.
+	Script string
+}
+
+// knownExporters are the names of the popular exporters and the lower-case substrings of their script paths.
+var knownExporters = []struct {
+	name     string
+	patterns []string
+}{
+	{hmdExporterName, []string{"dcshmd/"}},
+	{"Tacview", []string{"tacview"}},
+	{"SRS", []string{"dcs-simpleradiostandalone", "dcs-srs"}},
+	{"DCS-BIOS", []string{"dcs-bios"}},
+	{"Helios", []string{"helios"}},
+	{"DCS-ExportScripts", []string{"dcs-exportscript"}},
+	{"SimShaker", []string{"simshaker"}},
+	{"VAICOM PRO", []string{"vaicom"}},
+}
+
+// inlineExporterName is the name of the callbacks defined in Export.lua itself.
+const inlineExporterName = "Export.lua code"
+
+// hmdExporterName is the name of the DCS HMD exporter, hmdFolderGlobal is set by its hook that loads the scripts
+// from the folder other than the default one.
+const (
+	hmdExporterName = "DCS HMD"
+	hmdFolderGlobal = "DCSHMD_Folder="
+)
+
+// Exporter is the script loaded by Export.lua or the callback defined in Export.lua itself.
+type Exporter struct {
+	// Name is the name of the known exporter or "unknown".
+	Name string
+	// Line is the line number of Export.lua starting from 1.
+	Line int
+	// Script is the path of the loaded script, it is empty for the callback defined in Export.lua.
+	Script string
+	// Hook reports whether the line is the hook of the exporter being checked.
+	Hook bool
+	// Protected reports whether the script is loaded in pcall, so its errors don't stop Export.lua.
+	Protected bool
+	// Missing reports whether the script doesn't exist.
+	Missing bool
+	// Overwrites are the callbacks the script defines without calling the previously defined ones.
+	Overwrites []string
+}
+
+func (e *Exporter) String() string {
+	if e.Script == "" {
+		return fmt.Sprintf("%s on line %d", e.Name, e.Line)
+	}
+
+	return fmt.Sprintf("%s (%s) on line %d", e.Name, e.Script, e.Line)
+}
+
+// Severity is the severity of the issue.
+type Severity int
+
+const (
+	// SeverityWarning is the issue that may break the other exporters.
+	SeverityWarning Severity = iota
+	// SeverityError is the issue that breaks the exporter being checked.
+	SeverityError
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+
+	return "warning"
+}
+
+// Issue is the problem found in Export.lua.
+type Issue struct {
+	Severity Severity
+	// Line is the line number of Export.lua the issue is found on, it is zero if the issue is not bound to a line.
+	Line    int
+	Message string
+	// Fixable reports whether Fix can fix the issue.
+	Fixable bool
+}
+
+func (i *Issue) String() string {
+	var b strings.Builder
+
+	b.WriteString(i.Severity.String())
+
+	if i.Line > 0 {
+		fmt.Fprintf(&b, ": line %d", i.Line)
+	}
+
+	b.WriteString(": ")
+	b.WriteString(i.Message)
+
+	if i.Fixable {
+		b.WriteString(" (fixable)")
+	}
+
+	return b.String()
+}
+
+// Report is the result of the Export.lua check.
+type Report struct {
+	Exporters []Exporter
+	Issues    []Issue
+
+	// lines of Export.lua with and without comments
+	lines []string
+	code  []string
+	// hookCallbacks are the callbacks defined by the hook script
+	hookCallbacks []string
+}
+
+// Fixable reports whether any of the issues can be fixed.
+func (r *Report) Fixable() bool {
+	for _, issue := range r.Issues {
+		if issue.Fixable {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasErrors reports whether any of the issues is an error, so the exporter doesn't work.
+func (r *Report) HasErrors() bool {
+	for _, issue := range r.Issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *Report) String() string {
+	var b strings.Builder
+
+	b.WriteString("exporters:\n")
+
+	if len(r.Exporters) == 0 {
+		b.WriteString("  none\n")
+	}
+
+	for i := range r.Exporters {
+		fmt.Fprintf(&b, "  %s\n", &r.Exporters[i])
+	}
+
+	if len(r.Issues) == 0 {
+		b.WriteString("no issues found\n")
+		return b.String()
+	}
+
+	b.WriteString("issues:\n")
+
+	for i := range r.Issues {
+		fmt.Fprintf(&b, "  %s\n", &r.Issues[i])
+	}
+
+	return b.String()
+}
+
+// Diagnose checks Export.lua at exportPath, fsys is the DCS write directory the script paths are relative to.
+// It lists the loaded exporters and reports the issues that break the exporter of the hook or the other ones:
+// the missing or duplicated hook, the missing scripts and the callbacks that are defined without calling
+// the previous ones after the scripts that define them.
+func Diagnose(fsys fs.FS, exportPath string, hook *Hook) (*Report, error) {
+	data, err := fs.ReadFile(fsys, exportPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read '%s' script: %w", exportPath, err)
+	}
+
+	r := &Report{}
+	if len(data) > 0 {
+		r.lines = strings.SplitAfter(string(data), "\n")
+		r.code = luaLines(string(data))
+	}
+
+	r.hookCallbacks = exportCallbacks
+
+	if err := r.findExporters(fsys, hook); err != nil {
+		return nil, err
+	}
+
+	r.checkHook(hook)
+	r.checkMissingScripts()
+	r.checkOverwrites()
+
+	return r, nil
+}
+
+// disabledLinePrefix comments out the line of Export.lua that loads the missing script.
+const disabledLinePrefix = "-- disabled by dcs-hmd, the script does not exist: "
+
+// Fix returns the contents of Export.lua with the fixable issues of the report fixed: the single hook line
+// is placed after the last exporter that overwrites the hook callbacks, otherwise it is the first line.
+// The lines above the hook that load only the missing scripts without pcall are commented out, since DCS
+// stops executing Export.lua on them.
+func Fix(r *Report, hook *Hook) []byte {
+	insertAt := r.hookPosition()
+	disabled := r.disabledLines(insertAt)
+
+	eol := "\n"
+	if len(r.lines) > 0 && strings.HasSuffix(r.lines[0], "\r\n") {
+		eol = "\r\n"
+	}
+
+	var b strings.Builder
+
+	if insertAt == 0 {
+		b.WriteString(hook.Line + eol)
+	}
+
+	for i, line := range r.lines {
+		switch {
+		case strings.Contains(r.code[i], hook.Line):
+			// the hook is written at insertAt
+		case disabled[i+1]:
+			b.WriteString(disabledLinePrefix + line)
+		default:
+			b.WriteString(line)
+		}
+
+		if i+1 == insertAt {
+			if !strings.HasSuffix(line, "\n") {
+				b.WriteString(eol)
+			}
+
+			b.WriteString(hook.Line + eol)
+		}
+	}
+
+	return []byte(b.String())
+}
+
+// hookPosition returns the line number Fix places the hook after, zero means the first line.
+func (r *Report) hookPosition() int {
+	insertAt := 0
+	for i := range r.Exporters {
+		e := &r.Exporters[i]
+		if !e.Hook && overwritesAny(e, r.hookCallbacks) {
+			insertAt = e.Line
+		}
+	}
+
+	return insertAt
+}
+
+// disabledLines returns the numbers of the lines above the hook placed after insertAt that Fix comments out:
+// the ones that load only the missing scripts without pcall.
+func (r *Report) disabledLines(insertAt int) map[int]bool {
+	disabled := make(map[int]bool)
+	kept := make(map[int]bool)
+
+	for i := range r.Exporters {
+		e := &r.Exporters[i]
+		if e.Line > insertAt {
+			continue
+		}
+
+		if e.Missing && !e.Protected && !e.Hook {
+			disabled[e.Line] = true
+		} else {
+			kept[e.Line] = true
+		}
+	}
+
+	// the line that loads the existing script too can't be commented out
+	for line := range kept {
+		delete(disabled, line)
+	}
+
+	return disabled
+}
+
+func (r *Report) findExporters(fsys fs.FS, hook *Hook) error {
+	_, captured := callbacks(r.code)
+
+	for i, code := range r.code {
+		line := i + 1
+
+		scripts := loadedScripts(code)
+		for _, script := range scripts {
+			e := Exporter{
+				Name:      exporterName(code, script, hook),
+				Line:      line,
+				Script:    script,
+				Hook:      strings.Contains(code, hook.Line) && strings.EqualFold(script, hook.Script),
+				Protected: strings.Contains(code, "pcall("),
+			}
+
+			if err := e.inspect(fsys); err != nil {
+				return err
+			}
+
+			if e.Hook && !e.Missing {
+				if err := r.inspectHook(fsys, script); err != nil {
+					return err
+				}
+			}
+
+			r.Exporters = append(r.Exporters, e)
+		}
+
+		if len(scripts) > 0 {
+			continue
+		}
+
+		defined, _ := callbacks([]string{code})
+		if overwrites := overwritten(defined, captured); len(overwrites) > 0 {
+			r.Exporters = append(r.Exporters, Exporter{Name: inlineExporterName, Line: line, Overwrites: overwrites})
+		}
+	}
+
+	return nil
+}
+
+// inspect finds out whether the script exists and which callbacks it overwrites. The scripts outside
+// of the write directory are not inspected.
+func (e *Exporter) inspect(fsys fs.FS) error {
+	if strings.Contains(e.Script, ":") || !fs.ValidPath(e.Script) {
+		return nil
+	}
+
+	data, err := fs.ReadFile(fsys, e.Script)
+	if errors.Is(err, fs.ErrNotExist) {
+		e.Missing = true
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to read '%s' script: %w", e.Script, err)
+	}
+
+	e.Overwrites = overwritten(callbacks(luaLines(string(data))))
+
+	return nil
+}
+
+// inspectHook finds out which callbacks the hook script defines.
+func (r *Report) inspectHook(fsys fs.FS, script string) error {
+	data, err := fs.ReadFile(fsys, script)
+	if err != nil {
+		return fmt.Errorf("failed to read '%s' script: %w", script, err)
+	}
+
+	if defined, _ := callbacks(luaLines(string(data))); len(defined) > 0 {
+		r.hookCallbacks = defined
+	}
+
+	return nil
+}
+
+func (r *Report) checkHook(hook *Hook) {
+	var hooks []*Exporter
+
+	for i := range r.Exporters {
+		if e := &r.Exporters[i]; e.Hook {
+			hooks = append(hooks, e)
+		}
+	}
+
+	if len(hooks) == 0 {
+		r.addIssue(SeverityError, 0, true, "the hook is missing, so the exporter is not loaded: %s", hook.Line)
+		return
+	}
+
+	if len(hooks) > 1 {
+		r.addIssue(SeverityWarning, hooks[1].Line, true, "the hook is present %d times, so the exporter is loaded more than once",
+			len(hooks))
+	}
+
+	if hooks[0].Missing {
+		r.addIssue(SeverityError, hooks[0].Line, false, "the exporter is not installed: '%s' does not exist", hooks[0].Script)
+	}
+}
+
+func (r *Report) checkMissingScripts() {
+	hook := r.firstHook()
+
+	// Fix either moves the hook above the missing script or comments the script out
+	insertAt := r.hookPosition()
+	disabled := r.disabledLines(insertAt)
+
+	for i := range r.Exporters {
+		e := &r.Exporters[i]
+		if !e.Missing || e.Hook {
+			continue
+		}
+
+		switch {
+		case e.Protected:
+			r.addIssue(SeverityWarning, e.Line, false, "%s does not exist", e)
+		case hook != nil && e.Line < hook.Line:
+			r.addIssue(SeverityError, e.Line, e.Line > insertAt || disabled[e.Line],
+				"%s does not exist, DCS stops executing Export.lua on it, so the hook is never reached", e)
+		default:
+			r.addIssue(SeverityWarning, e.Line, false,
+				"%s does not exist, DCS stops executing Export.lua on it, so the lines after it are ignored", e)
+		}
+	}
+}
+
+func (r *Report) checkOverwrites() {
+	hook := r.firstHook()
+
+	for i := range r.Exporters {
+		e := &r.Exporters[i]
+		if e.Hook || len(e.Overwrites) == 0 {
+			continue
+		}
+
+		callbacks := strings.Join(e.Overwrites, ", ")
+
+		if hook != nil && e.Line > hook.Line && overwritesAny(e, r.hookCallbacks) {
+			r.addIssue(SeverityError, e.Line, true,
+				"%s overwrites %s without calling the previous callbacks, so the exporter callbacks are never called",
+				e, callbacks)
+		}
+
+		var broken []string
+
+		for j := range r.Exporters {
+			if prev := &r.Exporters[j]; !prev.Hook && prev.Line < e.Line && !slices.Contains(broken, prev.Name) {
+				broken = append(broken, prev.Name)
+			}
+		}
+
+		if len(broken) > 0 {
+			r.addIssue(SeverityWarning, e.Line, false,
+				"%s overwrites %s without calling the previous callbacks, it may break %s loaded before it",
+				e, callbacks, strings.Join(broken, ", "))
+		}
+	}
+}
+
+func (r *Report) firstHook() *Exporter {
+	for i := range r.Exporters {
+		if e := &r.Exporters[i]; e.Hook {
+			return e
+		}
+	}
+
+	return nil
+}
+
+func (r *Report) addIssue(severity Severity, line int, fixable bool, format string, args ...any) {
+	r.Issues = append(r.Issues, Issue{
+		Severity: severity,
+		Line:     line,
+		Message:  fmt.Sprintf(format, args...),
+		Fixable:  fixable,
+	})
+}
+
+// exporterName returns the name of the known exporter the script loaded by the line of code belongs to.
+// DCS HMD installed to any folder is recognized by the hook or by the folder global the line sets.
+func exporterName(code, script string, hook *Hook) string {
+	if strings.EqualFold(script, hook.Script) || strings.Contains(code, hmdFolderGlobal) {
+		return hmdExporterName
+	}
+
+	script = strings.ToLower(path.Clean(script))
+
+	for _, known := range knownExporters {
+		for _, pattern := range known.patterns {
+			if strings.Contains(script, pattern) {
+				return known.name
+			}
+		}
+	}
+
+	return "unknown"
+}
+
+func overwritesAny(e *Exporter, callbacks []string) bool {
+	for _, name := range e.Overwrites {
+		if slices.Contains(callbacks, name) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,262 @@
+package installer_test
+
+import (
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimchansky/dcs-hmd/installer"
+)
+
+const (
+	hookLine    = "local lfs=require('lfs');dofile(lfs.writedir()..'Scripts/DCSHMD/Export.lua')"
+	tacviewLine = "local Tacviewlfs=require('lfs');dofile(Tacviewlfs.writedir()..'Scripts/TacviewGameExport.lua')"
+	biosLine    = `dofile(lfs.writedir()..[[Scripts\DCS-BIOS\BIOS.lua]])`
+	missingLine = "dofile(lfs.writedir()..'Scripts/Missing.lua')"
+	srsLine     = `pcall(function() local dcsSr=require('lfs');dofile(dcsSr.writedir()..[[Mods\Services\DCS-SRS\Scripts\DCS-SimpleRadioStandalone.lua]]); end,nil)`
+
+	exportPath = "Scripts/Export.lua"
+
+	chainingScript = `local PrevExport = {}
+PrevExport.LuaExportStart = LuaExportStart
+PrevExport.LuaExportStop = LuaExportStop
+function LuaExportStart()
+    if PrevExport.LuaExportStart then PrevExport.LuaExportStart() end
+end
+function LuaExportStop()
+    if PrevExport.LuaExportStop then PrevExport.LuaExportStop() end
+end
+`
+
+	overwritingScript = `-- LuaExportStart = LuaExportStart is not called
+function LuaExportStart()
+end
+LuaExportStop = function()
+end
+`
+)
+
+var hook = installer.Hook{Line: hookLine, Script: "Scripts/DCSHMD/Export.lua"}
+
+func scriptsFS(exportLua string) fstest.MapFS {
+	fsys := fstest.MapFS{
+		"Scripts/DCSHMD/Export.lua":       {Data: []byte(chainingScript)},
+		"Scripts/TacviewGameExport.lua":   {Data: []byte(chainingScript)},
+		"Scripts/DCS-BIOS/BIOS.lua":       {Data: []byte(overwritingScript)},
+		"Mods/Services/DCS-SRS/Scripts/x": {},
+	}
+
+	if exportLua != "" {
+		fsys[exportPath] = &fstest.MapFile{Data: []byte(exportLua)}
+	}
+
+	return fsys
+}
+
+func TestDiagnose_Exporters(t *testing.T) {
+	exportLua := hookLine + "\n" + tacviewLine + "\n" + "-- " + biosLine + "\n" + srsLine + "\n"
+
+	r, err := installer.Diagnose(scriptsFS(exportLua), exportPath, &hook)
+	require.NoError(t, err)
+
+	require.Equal(t, []installer.Exporter{
+		{Name: "DCS HMD", Line: 1, Script: "Scripts/DCSHMD/Export.lua", Hook: true},
+		{Name: "Tacview", Line: 2, Script: "Scripts/TacviewGameExport.lua"},
+		{Name: "SRS", Line: 4, Script: "Mods/Services/DCS-SRS/Scripts/DCS-SimpleRadioStandalone.lua", Protected: true, Missing: true},
+	}, r.Exporters)
+
+	require.Equal(t, []installer.Issue{
+		{Severity: installer.SeverityWarning, Line: 4, Message: "SRS (Mods/Services/DCS-SRS/Scripts/DCS-SimpleRadioStandalone.lua) on line 4 does not exist"},
+	}, r.Issues)
+	require.False(t, r.Fixable())
+	require.False(t, r.HasErrors())
+}
+
+func TestDiagnose_ScriptsFolder(t *testing.T) {
+	const (
+		testLine   = "local lfs=require('lfs');DCSHMD_Folder='DCSHMD-Test';dofile(lfs.writedir()..'Scripts/DCSHMD-Test/Export.lua')"
+		testScript = "Scripts/DCSHMD-Test/Export.lua"
+	)
+
+	testHook := installer.Hook{Line: testLine, Script: testScript}
+
+	fsys := scriptsFS(testLine + "\n" + hookLine + "\n")
+	fsys[testScript] = &fstest.MapFile{Data: []byte(chainingScript)}
+
+	// both installations are recognized whichever of them is checked
+	for _, h := range []installer.Hook{hook, testHook} {
+		r, err := installer.Diagnose(fsys, exportPath, &h)
+		require.NoError(t, err)
+
+		require.Equal(t, []installer.Exporter{
+			{Name: "DCS HMD", Line: 1, Script: testScript, Hook: h == testHook},
+			{Name: "DCS HMD", Line: 2, Script: "Scripts/DCSHMD/Export.lua", Hook: h == hook},
+		}, r.Exporters)
+		require.Empty(t, r.Issues)
+	}
+}
+
+func TestDiagnose_Issues(t *testing.T) {
+	tests := []struct {
+		name      string
+		exportLua string
+		want      []installer.Issue
+	}{
+		{
+			name:      "healthy",
+			exportLua: hookLine + "\n" + tacviewLine + "\n",
+		},
+		{
+			name: "no Export.lua",
+			want: []installer.Issue{
+				{Severity: installer.SeverityError, Message: "the hook is missing, so the exporter is not loaded: " + hookLine, Fixable: true},
+			},
+		},
+		{
+			name:      "commented out hook",
+			exportLua: "--" + hookLine + "\n",
+			want: []installer.Issue{
+				{Severity: installer.SeverityError, Message: "the hook is missing, so the exporter is not loaded: " + hookLine, Fixable: true},
+			},
+		},
+		{
+			name:      "duplicated hook",
+			exportLua: hookLine + "\n" + tacviewLine + "\n" + hookLine + "\n",
+			want: []installer.Issue{
+				{Severity: installer.SeverityWarning, Line: 3, Message: "the hook is present 2 times, so the exporter is loaded more than once", Fixable: true},
+			},
+		},
+		{
+			name:      "overwriting script after hook",
+			exportLua: tacviewLine + "\n" + hookLine + "\n" + biosLine + "\n",
+			want: []installer.Issue{
+				{Severity: installer.SeverityError, Line: 3, Message: `DCS-BIOS (Scripts/DCS-BIOS/BIOS.lua) on line 3 overwrites LuaExportStart, LuaExportStop without calling the previous callbacks, so the exporter callbacks are never called`, Fixable: true},
+				{Severity: installer.SeverityWarning, Line: 3, Message: `DCS-BIOS (Scripts/DCS-BIOS/BIOS.lua) on line 3 overwrites LuaExportStart, LuaExportStop without calling the previous callbacks, it may break Tacview loaded before it`},
+			},
+		},
+		{
+			name:      "overwriting code after hook",
+			exportLua: hookLine + "\nfunction LuaExportStop()\nend\n",
+			want: []installer.Issue{
+				{Severity: installer.SeverityError, Line: 2, Message: `Export.lua code on line 2 overwrites LuaExportStop without calling the previous callbacks, so the exporter callbacks are never called`, Fixable: true},
+			},
+		},
+		{
+			name:      "missing script before hook",
+			exportLua: missingLine + "\n" + hookLine + "\n",
+			want: []installer.Issue{
+				{Severity: installer.SeverityError, Line: 1, Message: `unknown (Scripts/Missing.lua) on line 1 does not exist, DCS stops executing Export.lua on it, so the hook is never reached`, Fixable: true},
+			},
+		},
+		{
+			name:      "missing script before overwriting script",
+			exportLua: missingLine + "\n" + biosLine + "\n" + hookLine + "\n",
+			want: []installer.Issue{
+				{Severity: installer.SeverityError, Line: 1, Message: `unknown (Scripts/Missing.lua) on line 1 does not exist, DCS stops executing Export.lua on it, so the hook is never reached`, Fixable: true},
+				{Severity: installer.SeverityWarning, Line: 2, Message: `DCS-BIOS (Scripts/DCS-BIOS/BIOS.lua) on line 2 overwrites LuaExportStart, LuaExportStop without calling the previous callbacks, it may break unknown loaded before it`},
+			},
+		},
+		{
+			name:      "missing script on the line of existing script",
+			exportLua: missingLine + ";" + biosLine + "\n" + hookLine + "\n",
+			want: []installer.Issue{
+				{Severity: installer.SeverityError, Line: 1, Message: `unknown (Scripts/Missing.lua) on line 1 does not exist, DCS stops executing Export.lua on it, so the hook is never reached`},
+			},
+		},
+		{
+			name:      "exporter is not installed",
+			exportLua: hookLine + "\n",
+			want: []installer.Issue{
+				{Severity: installer.SeverityError, Line: 1, Message: `the exporter is not installed: 'Scripts/DCSHMD/Export.lua' does not exist`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := scriptsFS(tt.exportLua)
+			if tt.name == "exporter is not installed" {
+				delete(fsys, "Scripts/DCSHMD/Export.lua")
+			}
+
+			r, err := installer.Diagnose(fsys, exportPath, &hook)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, r.Issues)
+			require.Equal(t, slices.ContainsFunc(tt.want, func(issue installer.Issue) bool {
+				return issue.Severity == installer.SeverityError
+			}), r.HasErrors())
+		})
+	}
+}
+
+func TestFix(t *testing.T) {
+	tests := []struct {
+		name      string
+		exportLua string
+		want      string
+	}{
+		{
+			name: "no Export.lua",
+			want: hookLine + "\n",
+		},
+		{
+			name:      "missing hook",
+			exportLua: tacviewLine + "\r\n" + srsLine,
+			want:      hookLine + "\r\n" + tacviewLine + "\r\n" + srsLine,
+		},
+		{
+			name:      "duplicated hook",
+			exportLua: hookLine + "\n" + tacviewLine + "\n" + hookLine + "\n",
+			want:      hookLine + "\n" + tacviewLine + "\n",
+		},
+		{
+			name:      "overwriting script after hook",
+			exportLua: hookLine + "\n" + tacviewLine + "\n" + biosLine + "\n" + srsLine + "\n",
+			want:      tacviewLine + "\n" + biosLine + "\n" + hookLine + "\n" + srsLine + "\n",
+		},
+		{
+			name:      "missing script before hook",
+			exportLua: missingLine + "\n" + hookLine + "\n",
+			want:      hookLine + "\n" + missingLine + "\n",
+		},
+		{
+			name:      "missing script before overwriting script",
+			exportLua: missingLine + "\r\n" + biosLine + "\r\n" + hookLine + "\r\n",
+			want:      "-- disabled by dcs-hmd, the script does not exist: " + missingLine + "\r\n" + biosLine + "\r\n" + hookLine + "\r\n",
+		},
+		{
+			name:      "missing hook after missing script",
+			exportLua: missingLine + "\n" + biosLine + "\n",
+			want:      "-- disabled by dcs-hmd, the script does not exist: " + missingLine + "\n" + biosLine + "\n" + hookLine + "\n",
+		},
+		{
+			name:      "overwriting script on the last line",
+			exportLua: hookLine + "\n" + biosLine,
+			want:      biosLine + "\n" + hookLine + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := scriptsFS(tt.exportLua)
+
+			r, err := installer.Diagnose(fsys, exportPath, &hook)
+			require.NoError(t, err)
+			require.True(t, r.Fixable())
+
+			fixed := installer.Fix(r, &hook)
+			require.Equal(t, tt.want, string(fixed))
+
+			fsys[exportPath] = &fstest.MapFile{Data: fixed}
+
+			r, err = installer.Diagnose(fsys, exportPath, &hook)
+			require.NoError(t, err)
+			require.False(t, r.Fixable(), r.String())
+			require.False(t, r.HasErrors(), r.String())
+		})
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !windows
+
+package installer
+
+// SystemKnownFolders returns the known folders of the current user, there are none on the systems other
+// than Windows, so only the home directory is searched.
+func SystemKnownFolders() KnownFolders {
+	return nil
+}
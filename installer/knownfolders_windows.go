@@ -0,0 +1,15 @@
+package installer
+
+import "golang.org/x/sys/windows"
+
+// SystemKnownFolders returns the known folders of the current user.
+func SystemKnownFolders() KnownFolders {
+	return windowsKnownFolders{}
+}
+
+type windowsKnownFolders struct{}
+
+// SavedGames returns the Saved Games folder registered in Windows, it follows the folder redirection.
+func (windowsKnownFolders) SavedGames() (string, error) {
+	return windows.KnownFolderPath(windows.FOLDERID_SavedGames, windows.KF_FLAG_DEFAULT)
+}
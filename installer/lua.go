@@ -0,0 +1,177 @@
+package installer
+
+import (
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// exportCallbacks are the global functions DCS calls in the exporters.
+var exportCallbacks = []string{
+	"LuaExportStart",
+	"LuaExportStop",
+	"LuaExportBeforeNextFrame",
+	"LuaExportAfterNextFrame",
+	"LuaExportActivityNextEvent",
+}
+
+var (
+	// loaderRe matches the calls that load the lua scripts.
+	loaderRe = regexp.MustCompile(`\b(?:dofile|loadfile)\s*\(`)
+	// scriptLiteralRe matches the string literals that end with the lua file name.
+	scriptLiteralRe = regexp.MustCompile(`(?i)'([^']*\.lua)'|"([^"]*\.lua)"|\[\[(.*?\.lua)\]\]`)
+	// definitionRe matches the global function definitions in both forms: "function name(" and "name = function".
+	definitionRe = regexp.MustCompile(`\bfunction\s+(LuaExport\w+)\s*\(|(?:^|[^.:\w])(LuaExport\w+)\s*=\s*function\b`)
+	// captureRe matches the assignments of the previous callbacks, e.g. "PrevExport.LuaExportStart = LuaExportStart".
+	captureRe = regexp.MustCompile(`=\s*(LuaExport\w+)\b\s*(?:[^\s(]|$)`)
+)
+
+// luaLines returns the lines of the lua script without the comments.
+func luaLines(script string) []string {
+	lines := strings.SplitAfter(script, "\n")
+
+	inBlock := false
+	for i, line := range lines {
+		lines[i], inBlock = stripComments(line, inBlock)
+	}
+
+	return lines
+}
+
+// ContainsCode reports whether the code of the lua script contains the line of code, the commented out one
+// doesn't count.
+func ContainsCode(script, code string) bool {
+	for _, line := range luaLines(script) {
+		if strings.Contains(line, code) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stripComments returns the code of the line without the comments, inBlock reports whether the line starts
+// inside the block comment and the returned one reports whether the next line does.
+// The string literals are kept, the long brackets of levels other than 0 are not supported.
+func stripComments(line string, inBlock bool) (code string, nextInBlock bool) {
+	var b strings.Builder
+
+	for i := 0; i < len(line); {
+		if inBlock {
+			end := strings.Index(line[i:], "]]")
+			if end < 0 {
+				return b.String(), true
+			}
+
+			i += end + len("]]")
+			inBlock = false
+
+			continue
+		}
+
+		switch c := line[i]; {
+		case c == '\'' || c == '"':
+			end := closingQuote(line, i)
+			b.WriteString(line[i:end])
+			i = end
+
+		case strings.HasPrefix(line[i:], "[["):
+			end := strings.Index(line[i:], "]]")
+			if end < 0 {
+				b.WriteString(line[i:])
+				return b.String(), false
+			}
+
+			b.WriteString(line[i : i+end+len("]]")])
+			i += end + len("]]")
+
+		case strings.HasPrefix(line[i:], "--"):
+			if strings.HasPrefix(line[i+len("--"):], "[[") {
+				i += len("--[[")
+				inBlock = true
+
+				continue
+			}
+
+			return b.String(), false
+
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+
+	return b.String(), inBlock
+}
+
+// closingQuote returns the position after the string literal that starts at the position.
+func closingQuote(line string, start int) int {
+	quote := line[start]
+
+	for i := start + 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1
+		}
+	}
+
+	return len(line)
+}
+
+// loadedScripts returns the paths of the scripts loaded by the line of code, the paths are cleaned
+// and use slashes, they are relative to the DCS write directory if loaded with lfs.writedir().
+func loadedScripts(code string) []string {
+	var scripts []string
+
+	for _, loc := range loaderRe.FindAllStringIndex(code, -1) {
+		m := scriptLiteralRe.FindStringSubmatch(code[loc[1]:])
+		if m == nil {
+			continue
+		}
+
+		script := m[1] + m[2] + m[3]
+		scripts = append(scripts, path.Clean(strings.ReplaceAll(script, `\`, "/")))
+	}
+
+	return scripts
+}
+
+// callbacks returns the export callbacks the code defines and the ones it saves to call them from its own.
+func callbacks(lines []string) (defined, captured []string) {
+	for _, code := range lines {
+		for _, m := range definitionRe.FindAllStringSubmatch(code, -1) {
+			defined = appendCallback(defined, m[1]+m[2])
+		}
+
+		for _, m := range captureRe.FindAllStringSubmatch(code, -1) {
+			captured = appendCallback(captured, m[1])
+		}
+	}
+
+	return defined, captured
+}
+
+// appendCallback appends the name of the export callback if it is not in the list yet.
+func appendCallback(names []string, name string) []string {
+	if !slices.Contains(exportCallbacks, name) || slices.Contains(names, name) {
+		return names
+	}
+
+	return append(names, name)
+}
+
+// overwritten returns the defined callbacks that are not captured.
+func overwritten(defined, captured []string) []string {
+	var names []string
+
+	for _, name := range defined {
+		if !slices.Contains(captured, name) {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
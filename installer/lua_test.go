@@ -0,0 +1,85 @@
+package installer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_luaLines(t *testing.T) {
+	script := "a = 1 -- comment\n" +
+		"b = '--not a comment' .. [[--neither]]\n" +
+		"--[[ block\n" +
+		"comment ]] c = 2\n" +
+		"d = \"\\\"--\" -- escaped quote\n"
+
+	require.Equal(t, []string{
+		"a = 1 ",
+		"b = '--not a comment' .. [[--neither]]\n",
+		"",
+		" c = 2\n",
+		"d = \"\\\"--\" ",
+		"",
+	}, luaLines(script))
+}
+
+func TestContainsCode(t *testing.T) {
+	const hook = "dofile(lfs.writedir()..'Scripts/DCSHMD/Export.lua')"
+
+	tests := []struct {
+		name   string
+		script string
+		want   bool
+	}{
+		{"line", "local lfs=require('lfs');" + hook + "\n", true},
+		{"last line", "a = 1\r\n" + hook, true},
+		{"commented out", "-- " + hook + "\n", false},
+		{"block comment", "--[[\n" + hook + "\n]]\n", false},
+		{"missing", "a = 1\n", false},
+		{"empty", "", false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, ContainsCode(tt.script, hook))
+		})
+	}
+}
+
+func Test_loadedScripts(t *testing.T) {
+	tests := []struct {
+		code string
+		want []string
+	}{
+		{"local lfs=require('lfs');dofile(lfs.writedir()..'Scripts/DCSHMD/Export.lua')", []string{"Scripts/DCSHMD/Export.lua"}},
+		{`dofile(lfs.writedir()..[[Scripts\DCS-BIOS\BIOS.lua]])`, []string{"Scripts/DCS-BIOS/BIOS.lua"}},
+		{`pcall(function() local l=require('lfs');dofile(l.writedir()..[[Mods\Services\DCS-SRS\Scripts\DCS-SimpleRadioStandalone.lua]]); end,nil)`,
+			[]string{"Mods/Services/DCS-SRS/Scripts/DCS-SimpleRadioStandalone.lua"}},
+		{`dofile("a.lua") loadfile("b.LUA")`, []string{"a.lua", "b.LUA"}},
+		{"local lfs=require('lfs')", nil},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.code, func(t *testing.T) {
+			require.Equal(t, tt.want, loadedScripts(tt.code))
+		})
+	}
+}
+
+func Test_callbacks(t *testing.T) {
+	lines := []string{
+		"PrevExport.LuaExportStart = LuaExportStart",
+		"function LuaExportStart() end",
+		"LuaExportStop = function() end",
+		"function LuaExportActivityNextEvent(t) local n = LuaExportStop() end",
+		"PrevExport.LuaExportBeforeNextFrame = function() end",
+		"function LuaExportUnknown() end",
+	}
+
+	defined, captured := callbacks(lines)
+	require.Equal(t, []string{"LuaExportStart", "LuaExportStop", "LuaExportActivityNextEvent"}, defined)
+	require.Equal(t, []string{"LuaExportStart"}, captured)
+	require.Equal(t, []string{"LuaExportStop", "LuaExportActivityNextEvent"}, overwritten(defined, captured))
+}
@@ -0,0 +1,235 @@
+package installer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// OpKind is the kind of the file operation.
+type OpKind int
+
+const (
+	// OpCreate creates the file.
+	OpCreate OpKind = iota
+	// OpOverwrite replaces the contents of the file.
+	OpOverwrite
+	// OpDelete deletes the file or the directory with all its files.
+	OpDelete
+	// OpPrependLine adds the line to the beginning of the file, the file is created if it doesn't exist.
+	OpPrependLine
+	// OpRemoveLine removes the lines that contain the line from the file.
+	OpRemoveLine
+)
+
+func (k OpKind) String() string {
+	switch k {
+	case OpCreate:
+		return "create"
+	case OpOverwrite:
+		return "overwrite"
+	case OpDelete:
+		return "delete"
+	case OpPrependLine:
+		return "prepend line to"
+	case OpRemoveLine:
+		return "remove line from"
+	default:
+		return fmt.Sprintf("OpKind(%d)", int(k))
+	}
+}
+
+// Op is the planned file operation.
+type Op struct {
+	Kind OpKind
+	// Path is the slash-separated path relative to the scripts directory.
+	Path string
+	// Old and New are the contents of the file before and after the operation, New is nil for OpDelete.
+	Old, New []byte
+}
+
+func (op *Op) String() string {
+	return op.Kind.String() + " " + op.Path
+}
+
+// Diff returns the unified diff of the file contents, it is empty for OpDelete.
+func (op *Op) Diff() string {
+	if op.Kind == OpDelete {
+		return ""
+	}
+
+	oldName := "a/" + op.Path
+	if op.Old == nil {
+		oldName = "/dev/null"
+	}
+
+	return UnifiedDiff(oldName, "b/"+op.Path, op.Old, op.New)
+}
+
+// Apply performs the operation in the transaction.
+func (op *Op) Apply(tx *Transaction) error {
+	if op.Kind == OpDelete {
+		if err := tx.RemoveAll(op.Path); err != nil {
+			return fmt.Errorf("failed to %s '%s': %w", op.Kind, op.Path, err)
+		}
+
+		return nil
+	}
+
+	if dir := path.Dir(op.Path); dir != "." {
+		if err := tx.MkdirAll(dir); err != nil {
+			return fmt.Errorf("failed to %s '%s': %w", op.Kind, op.Path, err)
+		}
+	}
+
+	if err := tx.WriteFile(op.Path, op.New); err != nil {
+		return fmt.Errorf("failed to %s '%s': %w", op.Kind, op.Path, err)
+	}
+
+	return nil
+}
+
+// Plan is the list of the file operations in the scripts directory, it is built from the current state
+// of the directory, so that it can be reviewed before it is applied.
+type Plan struct {
+	Ops []Op
+}
+
+// Empty reports whether there is nothing to do.
+func (p *Plan) Empty() bool {
+	return len(p.Ops) == 0
+}
+
+// String returns the list of the operations with the unified diffs of the line operations.
+func (p *Plan) String() string {
+	if p.Empty() {
+		return "nothing to do\n"
+	}
+
+	var b strings.Builder
+
+	for i := range p.Ops {
+		op := &p.Ops[i]
+		fmt.Fprintln(&b, op)
+
+		if op.Kind == OpPrependLine || op.Kind == OpRemoveLine {
+			b.WriteString(op.Diff())
+		}
+	}
+
+	return b.String()
+}
+
+// WriteFile plans creating the file or overwriting it if its contents differ.
+func (p *Plan) WriteFile(fsys fs.FS, name string, data []byte) error {
+	old, err := readFile(fsys, name)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case old == nil:
+		p.Ops = append(p.Ops, Op{Kind: OpCreate, Path: name, New: data})
+	case !bytes.Equal(old, data):
+		p.Ops = append(p.Ops, Op{Kind: OpOverwrite, Path: name, Old: old, New: data})
+	}
+
+	return nil
+}
+
+// Delete plans deleting the file or the directory, the files of the directory are listed before it.
+// Nothing is planned if it doesn't exist.
+func (p *Plan) Delete(fsys fs.FS, name string) error {
+	err := fs.WalkDir(fsys, name, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		old, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+
+		p.Ops = append(p.Ops, Op{Kind: OpDelete, Path: path, Old: old})
+
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to read '%s': %w", name, err)
+	}
+
+	if info, err := fs.Stat(fsys, name); err == nil && info.IsDir() {
+		p.Ops = append(p.Ops, Op{Kind: OpDelete, Path: name})
+	}
+
+	return nil
+}
+
+// PrependLine plans adding the line to the beginning of the file if the file doesn't contain it.
+func (p *Plan) PrependLine(fsys fs.FS, name, line string) error {
+	old, err := readFile(fsys, name)
+	if err != nil {
+		return err
+	}
+
+	if bytes.Contains(old, []byte(line)) {
+		return nil
+	}
+
+	p.Ops = append(p.Ops, Op{Kind: OpPrependLine, Path: name, Old: old, New: append([]byte(line+"\n"), old...)})
+
+	return nil
+}
+
+// RemoveLine plans removing the lines that contain the line from the file, the line endings are kept.
+func (p *Plan) RemoveLine(fsys fs.FS, name, line string) error {
+	old, err := readFile(fsys, name)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Contains(old, []byte(line)) {
+		return nil
+	}
+
+	data := make([]byte, 0, len(old))
+
+	for _, l := range bytes.SplitAfter(old, []byte("\n")) {
+		if !bytes.Contains(l, []byte(line)) {
+			data = append(data, l...)
+		}
+	}
+
+	p.Ops = append(p.Ops, Op{Kind: OpRemoveLine, Path: name, Old: old, New: data})
+
+	return nil
+}
+
+// readFile returns the contents of the file or nil if it doesn't exist.
+func readFile(fsys fs.FS, name string) ([]byte, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read '%s': %w", name, err)
+	}
+
+	if data == nil {
+		data = []byte{}
+	}
+
+	return data, nil
+}
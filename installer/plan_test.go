@@ -0,0 +1,85 @@
+package installer_test
+
+import (
+	"os"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimchansky/dcs-hmd/installer"
+)
+
+func TestPlan(t *testing.T) {
+	fsys := fstest.MapFS{
+		"Export.lua":         {Data: []byte("tacview\r\n" + hookLine + "\r\n")},
+		"DCSHMD/Core.lua":    {Data: []byte("core")},
+		"DCSHMD/Udp.lua":     {Data: []byte("old udp")},
+		"DCSHMD/Sub/Old.lua": {Data: []byte("old")},
+	}
+
+	var p installer.Plan
+	require.NoError(t, p.WriteFile(fsys, "DCSHMD/Core.lua", []byte("core")))
+	require.NoError(t, p.WriteFile(fsys, "DCSHMD/Udp.lua", []byte("udp")))
+	require.NoError(t, p.WriteFile(fsys, "DCSHMD/Config.lua", []byte("config")))
+	require.NoError(t, p.Delete(fsys, "DCSHMD/Sub"))
+	require.NoError(t, p.Delete(fsys, "DCSHMD/Missing.lua"))
+	require.NoError(t, p.PrependLine(fsys, "Export.lua", hookLine))
+	require.NoError(t, p.RemoveLine(fsys, "Export.lua", hookLine))
+	require.NoError(t, p.PrependLine(fsys, "Missing/Export.lua", hookLine))
+
+	require.Equal(t, []installer.Op{
+		{Kind: installer.OpOverwrite, Path: "DCSHMD/Udp.lua", Old: []byte("old udp"), New: []byte("udp")},
+		{Kind: installer.OpCreate, Path: "DCSHMD/Config.lua", New: []byte("config")},
+		{Kind: installer.OpDelete, Path: "DCSHMD/Sub/Old.lua", Old: []byte("old")},
+		{Kind: installer.OpDelete, Path: "DCSHMD/Sub"},
+		{Kind: installer.OpRemoveLine, Path: "Export.lua", Old: []byte("tacview\r\n" + hookLine + "\r\n"), New: []byte("tacview\r\n")},
+		{Kind: installer.OpPrependLine, Path: "Missing/Export.lua", New: []byte(hookLine + "\n")},
+	}, p.Ops)
+
+	require.Equal(t, "overwrite DCSHMD/Udp.lua\n"+
+		"create DCSHMD/Config.lua\n"+
+		"delete DCSHMD/Sub/Old.lua\n"+
+		"delete DCSHMD/Sub\n"+
+		"remove line from Export.lua\n"+
+		"--- a/Export.lua\n+++ b/Export.lua\n@@ -1,2 +1 @@\n tacview\r\n-"+hookLine+"\r\n"+
+		"prepend line to Missing/Export.lua\n"+
+		"--- /dev/null\n+++ b/Missing/Export.lua\n@@ -0,0 +1 @@\n+"+hookLine+"\n", p.String())
+}
+
+func TestPlan_Apply(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"Export.lua":         "tacview\n",
+		"DCSHMD/Core.lua":    "old core",
+		"DCSHMD/Sub/Old.lua": "old",
+	})
+
+	before := readFiles(t, dir)
+	fsys := os.DirFS(dir)
+
+	var p installer.Plan
+	require.NoError(t, p.WriteFile(fsys, "DCSHMD/Core.lua", []byte("core")))
+	require.NoError(t, p.WriteFile(fsys, "DCSHMD/New/Config.lua", []byte("config")))
+	require.NoError(t, p.Delete(fsys, "DCSHMD/Sub"))
+	require.NoError(t, p.PrependLine(fsys, "Export.lua", "hook"))
+
+	tx, err := installer.Begin(dir, time.Now())
+	require.NoError(t, err)
+
+	for i := range p.Ops {
+		require.NoError(t, p.Ops[i].Apply(tx))
+	}
+
+	require.NoError(t, tx.Commit())
+	require.Equal(t, map[string]string{
+		"Export.lua":            "hook\ntacview\n",
+		"DCSHMD/Core.lua":       "core",
+		"DCSHMD/New/Config.lua": "config",
+	}, readFiles(t, dir))
+
+	_, err = installer.Rollback(dir)
+	require.NoError(t, err)
+	require.Equal(t, before, readFiles(t, dir))
+}
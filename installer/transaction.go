@@ -0,0 +1,475 @@
+package installer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	// BackupDirName is the name of the directory in the scripts directory the backups are kept in.
+	BackupDirName = "DCSHMD-backup"
+
+	// MaxBackups is the number of the backups kept, the older ones are removed on commit.
+	MaxBackups = 10
+
+	// backupTimeLayout is the layout of the backup names, they are sorted in chronological order.
+	backupTimeLayout = "20060102-150405.000"
+
+	// backupSuffixFormat is the suffix of the backups started in the same millisecond, the suffixed names are
+	// sorted after the name without the suffix and before the name of the next millisecond.
+	backupSuffixFormat = "-%03d"
+	maxBackupSuffix    = 999
+
+	manifestFileName = "manifest.json"
+	filesDirName     = "files"
+)
+
+// ErrNoBackup is returned by Rollback if there is no backup to restore.
+var ErrNoBackup = errors.New("no backup to restore")
+
+// manifest lists the paths changed by the transaction in the order they were touched first.
+type manifest struct {
+	Entries []manifestEntry `json:"entries"`
+}
+
+// manifestEntry is the state of the path before the transaction.
+type manifestEntry struct {
+	// Path is the slash-separated path relative to the scripts directory.
+	Path string `json:"path"`
+	// Dir reports whether the path is a directory, the directories are not backed up, they are only created.
+	Dir bool `json:"dir,omitempty"`
+	// Existed reports whether the path existed, the file content is backed up if it did.
+	Existed bool `json:"existed"`
+}
+
+// Transaction changes the files in the scripts directory, every file is backed up before it is changed
+// or removed for the first time, so that the changes can be undone by Abort or, after Commit, by Rollback.
+// The files are written atomically, a half-written file is never left behind. It is not thread-safe.
+type Transaction struct {
+	dir       string
+	backupDir string
+
+	manifest manifest
+	touched  map[string]struct{}
+}
+
+// Begin starts the transaction in the scripts directory, the backup is named after the time. The backups
+// of the transactions started in the same millisecond get the suffixes, so that they are never shared.
+func Begin(dir string, now time.Time) (*Transaction, error) {
+	backupDir, err := createBackupDir(filepath.Join(dir, BackupDirName), now)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Mkdir(filepath.Join(backupDir, filesDirName), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory '%s': %w", backupDir, err)
+	}
+
+	return &Transaction{
+		dir:       dir,
+		backupDir: backupDir,
+		touched:   make(map[string]struct{}),
+	}, nil
+}
+
+// BackupDir returns the directory the backup of the transaction is kept in.
+func (t *Transaction) BackupDir() string {
+	return t.backupDir
+}
+
+// WriteFile backs up the file and replaces it atomically, name is the slash-separated path relative to
+// the scripts directory.
+func (t *Transaction) WriteFile(name string, data []byte) error {
+	if err := t.backup(name); err != nil {
+		return err
+	}
+
+	return WriteFileAtomic(t.path(name), data, 0644)
+}
+
+// MkdirAll creates the directory with its parents, the created directories are removed by the rollback.
+func (t *Transaction) MkdirAll(name string) error {
+	var created []string
+
+	for p := name; p != "." && p != "/"; p = path.Dir(p) {
+		if _, err := os.Stat(t.path(p)); err == nil {
+			break
+		}
+
+		created = append(created, p)
+	}
+
+	// the parents are recorded first, so that they are removed after their subdirectories
+	for i := len(created) - 1; i >= 0; i-- {
+		if err := t.record(manifestEntry{Path: created[i], Dir: true}); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(t.path(name), 0755); err != nil {
+		return fmt.Errorf("failed to create directory '%s': %w", t.path(name), err)
+	}
+
+	return nil
+}
+
+// Remove backs up the file and removes it, it does nothing if the file doesn't exist.
+func (t *Transaction) Remove(name string) error {
+	if err := t.backup(name); err != nil {
+		return err
+	}
+
+	if err := os.Remove(t.path(name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove file '%s': %w", t.path(name), err)
+	}
+
+	return nil
+}
+
+// RemoveAll backs up all the files in the directory and removes it, it does nothing if it doesn't exist.
+func (t *Transaction) RemoveAll(name string) error {
+	root := t.path(name)
+
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(t.dir, p)
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return t.record(manifestEntry{Path: filepath.ToSlash(rel), Dir: true, Existed: true})
+		}
+
+		return t.backup(filepath.ToSlash(rel))
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to back up directory '%s': %w", root, err)
+	}
+
+	if err := os.RemoveAll(root); err != nil {
+		return fmt.Errorf("failed to remove directory '%s': %w", root, err)
+	}
+
+	return nil
+}
+
+// Commit keeps the backup for Rollback and removes the oldest backups, so that MaxBackups are left.
+// The backup is removed if nothing has been changed.
+func (t *Transaction) Commit() error {
+	if len(t.manifest.Entries) == 0 {
+		return t.removeBackup()
+	}
+
+	return pruneBackups(filepath.Join(t.dir, BackupDirName), MaxBackups)
+}
+
+// Abort restores the state before the transaction and removes the backup.
+func (t *Transaction) Abort() error {
+	if err := restore(t.dir, t.backupDir, &t.manifest); err != nil {
+		return err
+	}
+
+	return t.removeBackup()
+}
+
+// Rollback restores the scripts directory from the latest backup and removes it, so that the next rollback
+// restores the state before the previous transaction. It returns the directory of the restored backup.
+func Rollback(dir string) (string, error) {
+	backups, err := listBackups(filepath.Join(dir, BackupDirName))
+	if err != nil {
+		return "", err
+	}
+
+	if len(backups) == 0 {
+		return "", ErrNoBackup
+	}
+
+	backupDir := backups[len(backups)-1]
+
+	data, err := os.ReadFile(filepath.Join(backupDir, manifestFileName))
+	if err != nil {
+		return "", fmt.Errorf("failed to read backup manifest: %w", err)
+	}
+
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return "", fmt.Errorf("failed to parse backup manifest '%s': %w", backupDir, err)
+	}
+
+	if err := restore(dir, backupDir, &m); err != nil {
+		return "", err
+	}
+
+	if err := os.RemoveAll(backupDir); err != nil {
+		return "", fmt.Errorf("failed to remove restored backup '%s': %w", backupDir, err)
+	}
+
+	return backupDir, nil
+}
+
+// WriteFileAtomic writes the data to the temporary file in the same directory and renames it to the file,
+// so that the file has either the previous or the new content even if the writing fails.
+func WriteFileAtomic(name string, data []byte, perm fs.FileMode) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for '%s': %w", name, err)
+	}
+
+	tmpName := f.Name()
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmpName)
+		}
+	}()
+
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write temporary file '%s': %w", tmpName, err)
+	}
+
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to sync temporary file '%s': %w", tmpName, err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file '%s': %w", tmpName, err)
+	}
+
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return fmt.Errorf("failed to set mode of temporary file '%s': %w", tmpName, err)
+	}
+
+	if err := os.Rename(tmpName, name); err != nil {
+		return fmt.Errorf("failed to replace '%s' file: %w", name, err)
+	}
+
+	return nil
+}
+
+func (t *Transaction) path(name string) string {
+	return filepath.Join(t.dir, filepath.FromSlash(name))
+}
+
+// backup copies the file into the backup if it is touched for the first time.
+func (t *Transaction) backup(name string) error {
+	if _, ok := t.touched[name]; ok {
+		return nil
+	}
+
+	data, err := os.ReadFile(t.path(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return t.record(manifestEntry{Path: name})
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to read '%s' file for backup: %w", t.path(name), err)
+	}
+
+	backupFile := filepath.Join(t.backupDir, filesDirName, filepath.FromSlash(name))
+
+	if err := os.MkdirAll(filepath.Dir(backupFile), 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory '%s': %w", filepath.Dir(backupFile), err)
+	}
+
+	if err := WriteFileAtomic(backupFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to back up '%s' file: %w", t.path(name), err)
+	}
+
+	return t.record(manifestEntry{Path: name, Existed: true})
+}
+
+// record adds the entry to the manifest if the path is touched for the first time, the manifest is saved
+// every time, so that the backup can be restored even if the installer is killed.
+func (t *Transaction) record(e manifestEntry) error {
+	if _, ok := t.touched[e.Path]; ok {
+		return nil
+	}
+
+	t.touched[e.Path] = struct{}{}
+	t.manifest.Entries = append(t.manifest.Entries, e)
+
+	data, err := json.MarshalIndent(&t.manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode backup manifest: %w", err)
+	}
+
+	if err := WriteFileAtomic(filepath.Join(t.backupDir, manifestFileName), data, 0644); err != nil {
+		return fmt.Errorf("failed to write backup manifest: %w", err)
+	}
+
+	return nil
+}
+
+func (t *Transaction) removeBackup() error {
+	if err := os.RemoveAll(t.backupDir); err != nil {
+		return fmt.Errorf("failed to remove backup '%s': %w", t.backupDir, err)
+	}
+
+	// the backup directory itself is removed only if it is empty
+	_ = os.Remove(filepath.Dir(t.backupDir))
+
+	return nil
+}
+
+// restore restores the entries of the manifest in the reverse order, so that the directories are created
+// before their files and the created directories are removed after their files.
+func restore(dir, backupDir string, m *manifest) error {
+	var errs []error
+
+	for i := len(m.Entries) - 1; i >= 0; i-- {
+		e := &m.Entries[i]
+		target := filepath.Join(dir, filepath.FromSlash(e.Path))
+
+		switch {
+		case e.Dir && e.Existed:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				errs = append(errs, fmt.Errorf("failed to restore directory '%s': %w", target, err))
+			}
+
+		case e.Dir:
+			if err := os.RemoveAll(target); err != nil {
+				errs = append(errs, fmt.Errorf("failed to remove directory '%s': %w", target, err))
+			}
+
+		case e.Existed:
+			if err := restoreFile(target, filepath.Join(backupDir, filesDirName, filepath.FromSlash(e.Path))); err != nil {
+				errs = append(errs, err)
+			}
+
+		default:
+			if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, fmt.Errorf("failed to remove file '%s': %w", target, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func restoreFile(target, backupFile string) error {
+	data, err := os.ReadFile(backupFile)
+	if err != nil {
+		return fmt.Errorf("failed to read backup of '%s' file: %w", target, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory '%s': %w", filepath.Dir(target), err)
+	}
+
+	if err := WriteFileAtomic(target, data, 0644); err != nil {
+		return fmt.Errorf("failed to restore '%s' file: %w", target, err)
+	}
+
+	return nil
+}
+
+// createBackupDir creates the new backup directory named after the time in the backups directory.
+func createBackupDir(backupsDir string, now time.Time) (string, error) {
+	if err := os.MkdirAll(backupsDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backups directory '%s': %w", backupsDir, err)
+	}
+
+	name := now.Format(backupTimeLayout)
+
+	for i := 0; i <= maxBackupSuffix; i++ {
+		backupDir := filepath.Join(backupsDir, name)
+		if i > 0 {
+			backupDir += fmt.Sprintf(backupSuffixFormat, i)
+		}
+
+		err := os.Mkdir(backupDir, 0755)
+		if err == nil {
+			return backupDir, nil
+		}
+
+		if !errors.Is(err, fs.ErrExist) {
+			return "", fmt.Errorf("failed to create backup directory '%s': %w", backupDir, err)
+		}
+	}
+
+	return "", fmt.Errorf("failed to create backup directory in '%s': too many backups at %s", backupsDir, name)
+}
+
+// isBackupName reports whether the name is the name of the backup directory: the time with the optional suffix.
+func isBackupName(name string) bool {
+	if len(name) < len(backupTimeLayout) {
+		return false
+	}
+
+	if _, err := time.Parse(backupTimeLayout, name[:len(backupTimeLayout)]); err != nil {
+		return false
+	}
+
+	suffix := name[len(backupTimeLayout):]
+	if suffix == "" {
+		return true
+	}
+
+	var i int
+	if _, err := fmt.Sscanf(suffix, backupSuffixFormat, &i); err != nil {
+		return false
+	}
+
+	return suffix == fmt.Sprintf(backupSuffixFormat, i) && i > 0
+}
+
+// listBackups returns the directories of the backups with the manifest from the oldest to the latest.
+func listBackups(backupsDir string) ([]string, error) {
+	entries, err := os.ReadDir(backupsDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backups directory '%s': %w", backupsDir, err)
+	}
+
+	var backups []string
+
+	for _, entry := range entries {
+		if !isBackupName(entry.Name()) || !entry.IsDir() {
+			continue
+		}
+
+		backupDir := filepath.Join(backupsDir, entry.Name())
+		if _, err := os.Stat(filepath.Join(backupDir, manifestFileName)); err == nil {
+			backups = append(backups, backupDir)
+		}
+	}
+
+	sort.Strings(backups)
+
+	return backups, nil
+}
+
+// pruneBackups removes the oldest backups, so that at most keep backups are left.
+func pruneBackups(backupsDir string, keep int) error {
+	backups, err := listBackups(backupsDir)
+	if err != nil {
+		return err
+	}
+
+	if len(backups) <= keep {
+		return nil
+	}
+
+	for _, backupDir := range backups[:len(backups)-keep] {
+		if err := os.RemoveAll(backupDir); err != nil {
+			return fmt.Errorf("failed to remove old backup '%s': %w", backupDir, err)
+		}
+	}
+
+	return nil
+}
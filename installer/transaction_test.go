@@ -0,0 +1,194 @@
+package installer_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimchansky/dcs-hmd/installer"
+)
+
+func TestTransaction_Rollback(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"Export.lua":         "tacview\n",
+		"DCSHMD/Core.lua":    "old core",
+		"DCSHMD/Removed.lua": "removed",
+	})
+
+	before := readFiles(t, dir)
+
+	now := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	install := func(now time.Time, core string) {
+		tx, err := installer.Begin(dir, now)
+		require.NoError(t, err)
+		require.NoError(t, tx.RemoveAll("DCSHMD"))
+		require.NoError(t, tx.MkdirAll("DCSHMD/Sub"))
+		require.NoError(t, tx.WriteFile("DCSHMD/Core.lua", []byte(core)))
+		require.NoError(t, tx.WriteFile("DCSHMD/Sub/Config.lua", []byte("config")))
+		require.NoError(t, tx.WriteFile("Export.lua", []byte("hook\ntacview\n")))
+		require.NoError(t, tx.Commit())
+	}
+
+	install(now, "new core")
+
+	installed := readFiles(t, dir)
+	require.Equal(t, map[string]string{
+		"Export.lua":            "hook\ntacview\n",
+		"DCSHMD/Core.lua":       "new core",
+		"DCSHMD/Sub/Config.lua": "config",
+	}, installed)
+
+	install(now.Add(time.Second), "newer core")
+
+	_, err := installer.Rollback(dir)
+	require.NoError(t, err)
+	require.Equal(t, installed, readFiles(t, dir))
+
+	_, err = installer.Rollback(dir)
+	require.NoError(t, err)
+	require.Equal(t, before, readFiles(t, dir))
+	require.NoDirExists(t, filepath.Join(dir, "DCSHMD", "Sub"))
+
+	_, err = installer.Rollback(dir)
+	require.ErrorIs(t, err, installer.ErrNoBackup)
+}
+
+func TestTransaction_SameMillisecond(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"Export.lua": "tacview\n"})
+
+	now := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	var backupDirs []string
+
+	for _, exportLua := range []string{"hook\ntacview\n", "tacview\nhook\n"} {
+		tx, err := installer.Begin(dir, now)
+		require.NoError(t, err)
+		require.NoError(t, tx.WriteFile("Export.lua", []byte(exportLua)))
+		require.NoError(t, tx.Commit())
+
+		backupDirs = append(backupDirs, tx.BackupDir())
+	}
+
+	require.Equal(t, []string{"20230401-120000.000", "20230401-120000.000-001"}, []string{
+		filepath.Base(backupDirs[0]), filepath.Base(backupDirs[1]),
+	})
+
+	// the backups are restored from the latest one
+	backupDir, err := installer.Rollback(dir)
+	require.NoError(t, err)
+	require.Equal(t, backupDirs[1], backupDir)
+	require.Equal(t, map[string]string{"Export.lua": "hook\ntacview\n"}, readFiles(t, dir))
+
+	backupDir, err = installer.Rollback(dir)
+	require.NoError(t, err)
+	require.Equal(t, backupDirs[0], backupDir)
+	require.Equal(t, map[string]string{"Export.lua": "tacview\n"}, readFiles(t, dir))
+}
+
+func TestTransaction_Abort(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"Export.lua": "tacview\n"})
+
+	before := readFiles(t, dir)
+
+	tx, err := installer.Begin(dir, time.Now())
+	require.NoError(t, err)
+	require.NoError(t, tx.MkdirAll("DCSHMD"))
+	require.NoError(t, tx.WriteFile("DCSHMD/Core.lua", []byte("core")))
+	require.NoError(t, tx.Remove("Export.lua"))
+	require.NoError(t, tx.Abort())
+
+	require.Equal(t, before, readFiles(t, dir))
+	require.NoDirExists(t, filepath.Join(dir, "DCSHMD"))
+	require.NoDirExists(t, filepath.Join(dir, installer.BackupDirName))
+}
+
+func TestTransaction_CommitKeepsMaxBackups(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	// the transaction without changes doesn't keep the backup
+	tx, err := installer.Begin(dir, now)
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+	require.NoDirExists(t, filepath.Join(dir, installer.BackupDirName))
+
+	for i := 0; i < installer.MaxBackups+2; i++ {
+		tx, err := installer.Begin(dir, now.Add(time.Duration(i)*time.Second))
+		require.NoError(t, err)
+		require.NoError(t, tx.WriteFile("Export.lua", []byte{byte('0' + i)}))
+		require.NoError(t, tx.Commit())
+	}
+
+	backups, err := os.ReadDir(filepath.Join(dir, installer.BackupDirName))
+	require.NoError(t, err)
+	require.Len(t, backups, installer.MaxBackups)
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "Export.lua")
+
+	require.NoError(t, installer.WriteFileAtomic(name, []byte("first"), 0644))
+	require.NoError(t, installer.WriteFileAtomic(name, []byte("second"), 0644))
+	require.Equal(t, map[string]string{"Export.lua": "second"}, readFiles(t, dir))
+
+	err := installer.WriteFileAtomic(filepath.Join(dir, "missing", "Export.lua"), []byte("third"), 0644)
+	require.True(t, errors.Is(err, fs.ErrNotExist), err)
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(name), 0755))
+		require.NoError(t, os.WriteFile(name, []byte(content), 0644))
+	}
+}
+
+// readFiles returns the contents of the files in the directory except the backups.
+func readFiles(t *testing.T, dir string) map[string]string {
+	t.Helper()
+
+	files := make(map[string]string)
+
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if d.Name() == installer.BackupDirName {
+				return fs.SkipDir
+			}
+
+			return nil
+		}
+
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+
+		files[filepath.ToSlash(rel)] = string(data)
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	return files
+}
@@ -63,6 +63,7 @@ var (
 	procEnumWindows              = modUser32.NewProc("EnumWindows")
 	procGetWindowLong            = modUser32.NewProc("GetWindowLongW")
 	procSetWindowLong            = modUser32.NewProc("SetWindowLongW")
+	procGetAsyncKeyState         = modUser32.NewProc("GetAsyncKeyState")
 
 	modKernel32             = syscall.NewLazyDLL("kernel32.dll")
 	procGetCurrentProcessId = modKernel32.NewProc("GetCurrentProcessId")
@@ -157,6 +158,14 @@ func SetWindowLong(hwnd HWND, index int, value uint32) uint32 {
 	return uint32(ret)
 }
 
+// GetAsyncKeyState returns the state of the key with the virtual-key code, the most significant bit is set
+// if the key is down.
+func GetAsyncKeyState(vKey int) int16 {
+	ret, _, _ := procGetAsyncKeyState.Call(uintptr(vKey))
+
+	return int16(ret)
+}
+
 func GetCurrentProcessId() uint32 {
 	r, _, err := procGetCurrentProcessId.Call()
 	if !IsErrSuccess(err) {
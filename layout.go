@@ -0,0 +1,212 @@
+package dcshmd
+
+import (
+	"image"
+	"math"
+
+	"golang.org/x/image/font"
+
+	"github.com/dimchansky/dcs-hmd/aircraft/ka-50/devices/rotorpitch"
+	"github.com/dimchansky/dcs-hmd/aircraft/ka-50/devices/rotorrpm"
+	"github.com/dimchansky/dcs-hmd/aircraft/ka-50/devices/verticalvelocity"
+	"github.com/dimchansky/dcs-hmd/gui/gmeter"
+	"github.com/dimchansky/dcs-hmd/gui/hover"
+	"github.com/dimchansky/dcs-hmd/gui/slipindicator"
+	"github.com/dimchansky/dcs-hmd/gui/velocityvector"
+)
+
+const (
+	indicatorHeight = 400
+	lineWidth       = 2
+
+	// velocityVectorRadius is the length of the ground velocity vector at velocityVectorMaxSpeed
+	velocityVectorRadius   = 60
+	velocityVectorMaxSpeed = 10 // m/s
+
+	// the hover display has the finer scales for the precise hover
+	hoverMaxSpeed         = 5  // m/s
+	hoverAccelerationLead = 1  // s
+	hoverTargetRange      = 20 // m
+)
+
+// hudLayout holds HUD dimensions for the actual screen size. All dimensions are defined for the reference
+// ScreenWidth x ScreenHeight screen and multiplied by the scale, so that vector images can be regenerated
+// at the native resolution instead of upscaling bitmaps.
+type hudLayout struct {
+	screenSize image.Point
+	scale      float64
+}
+
+// newHUDLayout returns the layout for the screen size given in native pixels.
+func newHUDLayout(screenSize image.Point) hudLayout {
+	scale := math.Min(
+		float64(screenSize.X)/ScreenWidth,
+		float64(screenSize.Y)/ScreenHeight,
+	)
+
+	return hudLayout{screenSize: screenSize, scale: scale}
+}
+
+// px scales the reference dimension to the screen pixels, it is at least one pixel, so that the images
+// are never empty, e.g. while the window is being resized.
+func (l *hudLayout) px(v float64) int {
+	if px := int(math.Round(v * l.scale)); px > 1 {
+		return px
+	}
+
+	return 1
+}
+
+// fontConfig returns font configuration with the size scaled to the screen.
+func (l *hudLayout) fontConfig(cfg *FontConfig) *FontConfig {
+	scaled := *cfg
+	scaled.Size *= l.scale
+
+	return &scaled
+}
+
+// indicatorsTop returns the screen Y coordinate of the indicators.
+func (l *hudLayout) indicatorsTop() float64 {
+	return float64(l.px(rowHeight))
+}
+
+// tapeRect returns the rectangle of the fixed window of the tape indicators.
+func (l *hudLayout) tapeRect() image.Rectangle {
+	xSpan := l.px(rowWidth / 2)
+	ySpan := l.px(rowHeight)
+
+	return image.Rectangle{
+		Min: image.Pt(xSpan, ySpan),
+		Max: image.Pt(l.px(rowWidth*2)+xSpan, l.px(indicatorHeight)-ySpan),
+	}
+}
+
+// overlayPos returns the screen position of the diagnostics overlay, it is to the right of the left tapes.
+func (l *hudLayout) overlayPos() image.Point {
+	return image.Pt(l.px(rowWidth*6)+l.px(rowWidth/2), int(l.indicatorsTop()))
+}
+
+// overlaySize returns the size of the diagnostics overlay, it spans the space between the tapes and ends above
+// the velocity vector and the hover display, so that it never covers them.
+func (l *hudLayout) overlaySize() image.Point {
+	pos := l.overlayPos()
+	width := l.screenSize.X - pos.X - l.px(rowWidth*3) - l.px(rowWidth/2)
+	height := l.screenCenter().Y - l.px(velocityVectorRadius+rowHeight) - pos.Y
+
+	return image.Pt(max(1, width), max(1, height))
+}
+
+// linkWarningPos returns the screen position of the link warning of the size, it is centered above the indicators.
+func (l *hudLayout) linkWarningPos(size image.Point) image.Point {
+	return image.Pt((l.screenSize.X-size.X)/2, 0)
+}
+
+func (l *hudLayout) rotorPitchConfig(ff font.Face) *rotorpitch.IndicatorConfig {
+	return &rotorpitch.IndicatorConfig{
+		Width:           l.px(rowWidth * 3),
+		Height:          l.px(indicatorHeight),
+		TickLength:      l.px(rowWidth),
+		MinorTickLength: l.px(rowWidth / 2),
+		LineWidth:       lineWidth * l.scale,
+		Color:           textColor,
+		BorderColor:     shadowColor,
+		Rect:            l.tapeRect(),
+		FontFace:        ff,
+	}
+}
+
+func (l *hudLayout) rotorRPMConfig(ff font.Face) *rotorrpm.IndicatorConfig {
+	return &rotorrpm.IndicatorConfig{
+		Width:           l.px(rowWidth * 3),
+		Height:          l.px(indicatorHeight),
+		TickLength:      l.px(rowWidth),
+		MinorTickLength: l.px(rowWidth * 3 / 4),
+		LineWidth:       lineWidth * l.scale,
+		Color:           textColor,
+		BorderColor:     shadowColor,
+		Rect:            l.tapeRect(),
+		FontFace:        ff,
+	}
+}
+
+func (l *hudLayout) verticalVelocityConfig(ff font.Face) *verticalvelocity.IndicatorConfig {
+	return &verticalvelocity.IndicatorConfig{
+		Width:           l.px(rowWidth * 3),
+		Height:          l.px(indicatorHeight),
+		TickLength:      l.px(rowWidth),
+		MinorTickLength: l.px(rowWidth * 3 / 4),
+		LineWidth:       lineWidth * l.scale,
+		Color:           textColor,
+		BorderColor:     shadowColor,
+		Rect:            l.tapeRect(),
+		FontFace:        ff,
+	}
+}
+
+// screenCenter returns the screen position of the center of the flight symbology.
+func (l *hudLayout) screenCenter() image.Point {
+	return image.Pt(l.screenSize.X/2, l.screenSize.Y/2)
+}
+
+// velocityVectorPos returns the screen position of the ground velocity vector or the hover display of the size,
+// it is centered on the screen.
+func (l *hudLayout) velocityVectorPos(size image.Point) image.Point {
+	return l.screenCenter().Sub(size.Div(2))
+}
+
+// gMeterPos returns the screen position of the G meter of the size, it is to the left of the velocity vector.
+func (l *hudLayout) gMeterPos(size image.Point) image.Point {
+	center := l.screenCenter()
+	return image.Pt(center.X-l.px(velocityVectorRadius+rowWidth)-size.X, center.Y-size.Y/2)
+}
+
+// slipIndicatorPos returns the screen position of the slip indicator of the size, it is below the velocity vector.
+func (l *hudLayout) slipIndicatorPos(size image.Point) image.Point {
+	center := l.screenCenter()
+	return image.Pt(center.X-size.X/2, center.Y+l.px(velocityVectorRadius+rowHeight))
+}
+
+func (l *hudLayout) gMeterConfig(ff font.Face) *gmeter.Config {
+	return &gmeter.Config{
+		Width:       l.px(rowWidth * 3),
+		Height:      l.px(rowHeight * 3 / 2),
+		Color:       textColor,
+		BorderColor: shadowColor,
+		FontFace:    ff,
+	}
+}
+
+func (l *hudLayout) slipIndicatorConfig() *slipindicator.Config {
+	return &slipindicator.Config{
+		Width:       l.px(rowWidth * 6),
+		Height:      l.px(rowHeight),
+		LineWidth:   lineWidth * l.scale,
+		Color:       textColor,
+		BorderColor: shadowColor,
+	}
+}
+
+func (l *hudLayout) velocityVectorConfig() *velocityvector.Config {
+	return &velocityvector.Config{
+		Radius:      l.px(velocityVectorRadius),
+		TipRadius:   float64(l.px(rowWidth / 4)),
+		CrossSize:   float64(l.px(rowWidth / 2)),
+		LineWidth:   lineWidth * l.scale,
+		Color:       textColor,
+		BorderColor: shadowColor,
+		MaxSpeed:    velocityVectorMaxSpeed,
+	}
+}
+
+func (l *hudLayout) hoverConfig() *hover.Config {
+	return &hover.Config{
+		Radius:           l.px(velocityVectorRadius),
+		SymbolSize:       float64(l.px(rowWidth / 4)),
+		LineWidth:        lineWidth * l.scale,
+		Color:            textColor,
+		BorderColor:      shadowColor,
+		MaxSpeed:         hoverMaxSpeed,
+		AccelerationLead: hoverAccelerationLead,
+		TargetRange:      hoverTargetRange,
+	}
+}
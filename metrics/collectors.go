@@ -0,0 +1,164 @@
+package metrics
+
+import (
+	"math"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/dimchansky/dcs-hmd/aircraft/ka-50/outputparser"
+	"github.com/dimchansky/dcs-hmd/updlistener"
+)
+
+// ListenerStats is a source of the listener counters, updlistener.Listener implements it.
+type ListenerStats interface {
+	Stats() updlistener.Stats
+}
+
+// ParserStats is a source of the parser counters, *outputparser.OutputParser implements it.
+type ParserStats interface {
+	Stats() outputparser.Stats
+}
+
+// ListenerCollector returns the collector of the listener counters.
+func ListenerCollector(l ListenerStats) Collector {
+	return CollectorFunc(func(w *Writer) {
+		s := l.Stats()
+
+		w.Family("dcshmd_listener_packets_total", TypeCounter, "Number of the handled packets.")
+		w.Sample("dcshmd_listener_packets_total", float64(s.Packets))
+		w.Family("dcshmd_listener_bytes_total", TypeCounter, "Number of bytes of the handled packets.")
+		w.Sample("dcshmd_listener_bytes_total", float64(s.Bytes))
+		w.Family("dcshmd_listener_truncated_packets_total", TypeCounter, "Number of the packets dropped because they are too large.")
+		w.Sample("dcshmd_listener_truncated_packets_total", float64(s.Truncated))
+		w.Family("dcshmd_listener_dropped_packets_total", TypeCounter, "Number of the packets from not allowed peers and failed reads.")
+		w.Sample("dcshmd_listener_dropped_packets_total", float64(s.Dropped))
+
+		if !s.LastPacketTime.IsZero() {
+			w.Family("dcshmd_listener_last_packet_timestamp_seconds", TypeGauge, "Time the last packet was handled.")
+			w.Sample("dcshmd_listener_last_packet_timestamp_seconds", float64(s.LastPacketTime.UnixNano())/1e9)
+		}
+	})
+}
+
+// ParserCollector returns the collector of the parser counters.
+func ParserCollector(p ParserStats) Collector {
+	return CollectorFunc(func(w *Writer) {
+		s := p.Stats()
+
+		w.Family("dcshmd_parser_messages_total", TypeCounter, "Number of the parsed messages.")
+		w.Sample("dcshmd_parser_messages_total", float64(s.Messages))
+		w.Family("dcshmd_parser_errors_total", TypeCounter, "Number of the messages and values that can't be parsed.")
+		w.Sample("dcshmd_parser_errors_total", float64(s.ParseErrors))
+		w.Family("dcshmd_parser_unknown_args_total", TypeCounter, "Number of the values of unknown arguments.")
+		w.Sample("dcshmd_parser_unknown_args_total", float64(s.UnknownArgs))
+
+		w.Family("dcshmd_parser_channel_updates_total", TypeCounter, "Number of the updates of the channel value.")
+
+		for _, ch := range s.Channels {
+			w.Sample("dcshmd_parser_channel_updates_total", float64(ch.Updates),
+				"arg", strconv.FormatUint(ch.Arg, 10), "channel", ch.Name)
+		}
+	})
+}
+
+// NewValues creates the latest values of the telemetry channels.
+func NewValues() *Values {
+	v := &Values{}
+	for i := range v.values {
+		v.values[i].Store(math.Float64bits(math.NaN()))
+	}
+
+	return v
+}
+
+// Values holds the latest values of the telemetry channels, it implements outputparser.ValuesSetter,
+// outputparser.FlightDataSetter and Collector interfaces. The values that have never been set are NaN.
+type Values struct {
+	values [valueCount]atomic.Uint64
+}
+
+const (
+	rotorPitchValue = iota
+	rotorRPMValue
+	verticalVelocityValue
+	indicatedAirspeedValue
+	altitudeAGLValue
+	velocityXValue
+	velocityYValue
+	velocityZValue
+	accelerationXValue
+	accelerationYValue
+	accelerationZValue
+	angleOfAttackValue
+	slipBallValue
+	headingValue
+	positionXValue
+	positionYValue
+	positionZValue
+	valueCount
+)
+
+var valueNames = [valueCount]string{
+	rotorPitchValue:        "rotor_pitch",
+	rotorRPMValue:          "rotor_rpm",
+	verticalVelocityValue:  "vertical_velocity",
+	indicatedAirspeedValue: "indicated_airspeed",
+	altitudeAGLValue:       "altitude_agl",
+	velocityXValue:         "velocity_x",
+	velocityYValue:         "velocity_y",
+	velocityZValue:         "velocity_z",
+	accelerationXValue:     "acceleration_x",
+	accelerationYValue:     "acceleration_y",
+	accelerationZValue:     "acceleration_z",
+	angleOfAttackValue:     "angle_of_attack",
+	slipBallValue:          "slip_ball",
+	headingValue:           "heading",
+	positionXValue:         "position_x",
+	positionYValue:         "position_y",
+	positionZValue:         "position_z",
+}
+
+// SetRotorPitch is thread-safe to update rotor pitch.
+func (v *Values) SetRotorPitch(val float64) {
+	v.values[rotorPitchValue].Store(math.Float64bits(val))
+}
+
+// SetRotorRPM is thread-safe to update rotor RPM.
+func (v *Values) SetRotorRPM(val float64) {
+	v.values[rotorRPMValue].Store(math.Float64bits(val))
+}
+
+// SetVerticalVelocity is thread-safe to update vertical velocity.
+func (v *Values) SetVerticalVelocity(val float64) {
+	v.values[verticalVelocityValue].Store(math.Float64bits(val))
+}
+
+// SetFlightData is thread-safe to update the flight data values.
+func (v *Values) SetFlightData(fd outputparser.FlightData) {
+	v.store(indicatedAirspeedValue, fd.IndicatedAirspeed)
+	v.store(altitudeAGLValue, fd.AltitudeAGL)
+	v.store(velocityXValue, fd.Velocity.X)
+	v.store(velocityYValue, fd.Velocity.Y)
+	v.store(velocityZValue, fd.Velocity.Z)
+	v.store(accelerationXValue, fd.Acceleration.X)
+	v.store(accelerationYValue, fd.Acceleration.Y)
+	v.store(accelerationZValue, fd.Acceleration.Z)
+	v.store(angleOfAttackValue, fd.AngleOfAttack)
+	v.store(slipBallValue, fd.SlipBall)
+	v.store(headingValue, fd.Heading)
+	v.store(positionXValue, fd.Position.X)
+	v.store(positionYValue, fd.Position.Y)
+	v.store(positionZValue, fd.Position.Z)
+}
+
+func (v *Values) store(i int, val float64) {
+	v.values[i].Store(math.Float64bits(val))
+}
+
+func (v *Values) Collect(w *Writer) {
+	w.Family("dcshmd_channel_value", TypeGauge, "Latest value of the telemetry channel.")
+
+	for i, name := range valueNames {
+		w.Sample("dcshmd_channel_value", math.Float64frombits(v.values[i].Load()), "channel", name)
+	}
+}
@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// frameBuckets are the upper bounds of the frame time histogram buckets in seconds.
+var frameBuckets = []float64{0.0005, 0.001, 0.002, 0.004, 0.008, 0.016, 0.033, 0.066, 0.1, 0.25}
+
+// NewHUD creates the HUD rendering metrics for the indicators with the names.
+func NewHUD(indicators ...string) *HUD {
+	h := &HUD{
+		update:  newHistogram(frameBuckets),
+		draw:    newHistogram(frameBuckets),
+		redraws: make(map[string]uint64, len(indicators)),
+	}
+
+	for _, name := range indicators {
+		h.redraws[name] = 0
+		h.indicators = append(h.indicators, name)
+	}
+
+	return h
+}
+
+// HUD holds the HUD rendering metrics, it is updated by the game loop and collected by the server.
+type HUD struct {
+	mutex      sync.Mutex
+	update     histogram
+	draw       histogram
+	indicators []string
+	redraws    map[string]uint64
+}
+
+// ObserveUpdate records the duration of HUD.Update.
+func (h *HUD) ObserveUpdate(d time.Duration) {
+	h.mutex.Lock()
+	h.update.observe(d.Seconds())
+	h.mutex.Unlock()
+}
+
+// ObserveDraw records the duration of HUD.Draw.
+func (h *HUD) ObserveDraw(d time.Duration) {
+	h.mutex.Lock()
+	h.draw.observe(d.Seconds())
+	h.mutex.Unlock()
+}
+
+// Redrawn counts the redraw of the indicator image on the screen.
+func (h *HUD) Redrawn(indicator string) {
+	h.mutex.Lock()
+	if _, ok := h.redraws[indicator]; !ok {
+		h.indicators = append(h.indicators, indicator)
+	}
+	h.redraws[indicator]++
+	h.mutex.Unlock()
+}
+
+func (h *HUD) Collect(w *Writer) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	w.Family("dcshmd_hud_update_duration_seconds", TypeHistogram, "Duration of the HUD update.")
+	h.update.write(w, "dcshmd_hud_update_duration_seconds")
+	w.Family("dcshmd_hud_draw_duration_seconds", TypeHistogram, "Duration of the HUD draw.")
+	h.draw.write(w, "dcshmd_hud_draw_duration_seconds")
+
+	w.Family("dcshmd_hud_redraws_total", TypeCounter, "Number of times the indicator image was drawn on the screen.")
+
+	for _, name := range h.indicators {
+		w.Sample("dcshmd_hud_redraws_total", float64(h.redraws[name]), "indicator", name)
+	}
+}
+
+// histogram counts the observations in the buckets, it is not thread-safe.
+type histogram struct {
+	bounds []float64
+	counts []uint64 // cumulative counts are calculated on write
+	count  uint64
+	sum    float64
+}
+
+func newHistogram(bounds []float64) histogram {
+	return histogram{bounds: bounds, counts: make([]uint64, len(bounds))}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, bound := range h.bounds {
+		if v <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+
+	h.count++
+	h.sum += v
+}
+
+func (h *histogram) write(w *Writer, name string) {
+	var cumulative uint64
+
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i]
+		w.Sample(name+"_bucket", float64(cumulative), "le", formatFloat(bound))
+	}
+
+	w.Sample(name+"_bucket", float64(h.count), "le", "+Inf")
+	w.Sample(name+"_sum", h.sum)
+	w.Sample(name+"_count", float64(h.count))
+}
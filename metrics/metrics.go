@@ -0,0 +1,97 @@
+// Package metrics provides an HTTP server that exposes the HMD telemetry and health metrics
+// in the Prometheus text exposition format.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Path is the path the metrics are served on.
+const Path = "/metrics"
+
+// contentType is the content type of the Prometheus text exposition format.
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Collector writes its metrics when the metrics are scraped, it must be thread-safe.
+type Collector interface {
+	Collect(w *Writer)
+}
+
+// CollectorFunc is an adapter to allow the use of ordinary functions as collectors.
+type CollectorFunc func(w *Writer)
+
+func (f CollectorFunc) Collect(w *Writer) {
+	f(w)
+}
+
+// New starts the HTTP server on the address, the server exposes the metrics of the collectors on Path.
+func New(address string, collectors ...Collector) (*Server, error) {
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen metrics address '%s': %w", address, err)
+	}
+
+	s := &Server{
+		closeCh:    make(chan struct{}),
+		ln:         ln,
+		collectors: collectors,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(Path, s.handleMetrics)
+
+	s.httpServer = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go s.serve()
+
+	return s, nil
+}
+
+type Server struct {
+	closeOnce sync.Once
+	closeErr  error
+	closeCh   chan struct{}
+
+	ln         net.Listener
+	httpServer *http.Server
+	collectors []Collector
+}
+
+// Addr returns the network address the server is listening on.
+func (s *Server) Addr() net.Addr {
+	return s.ln.Addr()
+}
+
+func (s *Server) Close() error {
+	s.closeOnce.Do(func() {
+		s.closeErr = s.httpServer.Shutdown(context.Background())
+		<-s.closeCh // wait until server is stopped
+	})
+
+	return s.closeErr
+}
+
+func (s *Server) serve() {
+	defer close(s.closeCh)
+
+	_ = s.httpServer.Serve(s.ln)
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+
+	mw := newWriter(w)
+	for _, c := range s.collectors {
+		c.Collect(mw)
+	}
+
+	_ = mw.flush()
+}
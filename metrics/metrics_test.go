@@ -0,0 +1,193 @@
+package metrics_test
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimchansky/dcs-hmd/aircraft/ka-50/outputparser"
+	"github.com/dimchansky/dcs-hmd/metrics"
+	"github.com/dimchansky/dcs-hmd/updlistener"
+)
+
+func TestServer_Metrics(t *testing.T) {
+	listener := listenerStatsFunc(func() updlistener.Stats {
+		return updlistener.Stats{
+			Packets:        10,
+			Bytes:          200,
+			Truncated:      1,
+			Dropped:        2,
+			LastPacketTime: time.Unix(1680350400, 500000000),
+		}
+	})
+
+	parser := outputparser.New(emptyValuesSetter{})
+	parser.HandleMessage([]byte("637beb27*53=0.9362:52=0.7792:1000=1\n"))
+
+	values := metrics.NewValues()
+	values.SetRotorRPM(85.5)
+	outputparser.New(values).HandleMessage([]byte("637beb27*10001=30.5:10010=-0.25:10011=1.5708:10014=-12.25\n"))
+
+	hud := metrics.NewHUD("rotor_pitch")
+	hud.ObserveUpdate(time.Millisecond)
+	hud.ObserveDraw(3 * time.Millisecond)
+	hud.ObserveDraw(time.Second)
+	hud.Redrawn("rotor_pitch")
+	hud.Redrawn("rotor_pitch")
+
+	s, err := metrics.New("127.0.0.1:0",
+		metrics.ListenerCollector(listener),
+		metrics.ParserCollector(parser),
+		values,
+		hud,
+	)
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, s.Close())
+	}()
+
+	resp, err := http.Get("http://" + s.Addr().String() + metrics.Path) //nolint:noctx // test request to the local server
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/plain; version=0.0.4; charset=utf-8", resp.Header.Get("Content-Type"))
+
+	require.Equal(t, `# HELP dcshmd_listener_packets_total Number of the handled packets.
+# TYPE dcshmd_listener_packets_total counter
+dcshmd_listener_packets_total 10
+# HELP dcshmd_listener_bytes_total Number of bytes of the handled packets.
+# TYPE dcshmd_listener_bytes_total counter
+dcshmd_listener_bytes_total 200
+# HELP dcshmd_listener_truncated_packets_total Number of the packets dropped because they are too large.
+# TYPE dcshmd_listener_truncated_packets_total counter
+dcshmd_listener_truncated_packets_total 1
+# HELP dcshmd_listener_dropped_packets_total Number of the packets from not allowed peers and failed reads.
+# TYPE dcshmd_listener_dropped_packets_total counter
+dcshmd_listener_dropped_packets_total 2
+# HELP dcshmd_listener_last_packet_timestamp_seconds Time the last packet was handled.
+# TYPE dcshmd_listener_last_packet_timestamp_seconds gauge
+dcshmd_listener_last_packet_timestamp_seconds 1.6803504005e+09
+# HELP dcshmd_parser_messages_total Number of the parsed messages.
+# TYPE dcshmd_parser_messages_total counter
+dcshmd_parser_messages_total 1
+# HELP dcshmd_parser_errors_total Number of the messages and values that can't be parsed.
+# TYPE dcshmd_parser_errors_total counter
+dcshmd_parser_errors_total 0
+# HELP dcshmd_parser_unknown_args_total Number of the values of unknown arguments.
+# TYPE dcshmd_parser_unknown_args_total counter
+dcshmd_parser_unknown_args_total 1
+# HELP dcshmd_parser_channel_updates_total Number of the updates of the channel value.
+# TYPE dcshmd_parser_channel_updates_total counter
+dcshmd_parser_channel_updates_total{arg="24",channel="vertical velocity"} 0
+dcshmd_parser_channel_updates_total{arg="52",channel="rotor RPM"} 1
+dcshmd_parser_channel_updates_total{arg="53",channel="rotor pitch"} 1
+dcshmd_parser_channel_updates_total{arg="10001",channel="indicated airspeed"} 0
+dcshmd_parser_channel_updates_total{arg="10002",channel="altitude AGL"} 0
+dcshmd_parser_channel_updates_total{arg="10003",channel="velocity X"} 0
+dcshmd_parser_channel_updates_total{arg="10004",channel="velocity Y"} 0
+dcshmd_parser_channel_updates_total{arg="10005",channel="velocity Z"} 0
+dcshmd_parser_channel_updates_total{arg="10006",channel="acceleration X"} 0
+dcshmd_parser_channel_updates_total{arg="10007",channel="acceleration Y"} 0
+dcshmd_parser_channel_updates_total{arg="10008",channel="acceleration Z"} 0
+dcshmd_parser_channel_updates_total{arg="10009",channel="angle of attack"} 0
+dcshmd_parser_channel_updates_total{arg="10010",channel="slip ball"} 0
+dcshmd_parser_channel_updates_total{arg="10011",channel="heading"} 0
+dcshmd_parser_channel_updates_total{arg="10012",channel="position X"} 0
+dcshmd_parser_channel_updates_total{arg="10013",channel="position Y"} 0
+dcshmd_parser_channel_updates_total{arg="10014",channel="position Z"} 0
+# HELP dcshmd_channel_value Latest value of the telemetry channel.
+# TYPE dcshmd_channel_value gauge
+dcshmd_channel_value{channel="rotor_pitch"} NaN
+dcshmd_channel_value{channel="rotor_rpm"} 85.5
+dcshmd_channel_value{channel="vertical_velocity"} NaN
+dcshmd_channel_value{channel="indicated_airspeed"} 30.5
+dcshmd_channel_value{channel="altitude_agl"} 0
+dcshmd_channel_value{channel="velocity_x"} 0
+dcshmd_channel_value{channel="velocity_y"} 0
+dcshmd_channel_value{channel="velocity_z"} 0
+dcshmd_channel_value{channel="acceleration_x"} 0
+dcshmd_channel_value{channel="acceleration_y"} 0
+dcshmd_channel_value{channel="acceleration_z"} 0
+dcshmd_channel_value{channel="angle_of_attack"} 0
+dcshmd_channel_value{channel="slip_ball"} -0.25
+dcshmd_channel_value{channel="heading"} 1.5708
+dcshmd_channel_value{channel="position_x"} 0
+dcshmd_channel_value{channel="position_y"} 0
+dcshmd_channel_value{channel="position_z"} -12.25
+# HELP dcshmd_hud_update_duration_seconds Duration of the HUD update.
+# TYPE dcshmd_hud_update_duration_seconds histogram
+dcshmd_hud_update_duration_seconds_bucket{le="0.0005"} 0
+dcshmd_hud_update_duration_seconds_bucket{le="0.001"} 1
+dcshmd_hud_update_duration_seconds_bucket{le="0.002"} 1
+dcshmd_hud_update_duration_seconds_bucket{le="0.004"} 1
+dcshmd_hud_update_duration_seconds_bucket{le="0.008"} 1
+dcshmd_hud_update_duration_seconds_bucket{le="0.016"} 1
+dcshmd_hud_update_duration_seconds_bucket{le="0.033"} 1
+dcshmd_hud_update_duration_seconds_bucket{le="0.066"} 1
+dcshmd_hud_update_duration_seconds_bucket{le="0.1"} 1
+dcshmd_hud_update_duration_seconds_bucket{le="0.25"} 1
+dcshmd_hud_update_duration_seconds_bucket{le="+Inf"} 1
+dcshmd_hud_update_duration_seconds_sum 0.001
+dcshmd_hud_update_duration_seconds_count 1
+# HELP dcshmd_hud_draw_duration_seconds Duration of the HUD draw.
+# TYPE dcshmd_hud_draw_duration_seconds histogram
+dcshmd_hud_draw_duration_seconds_bucket{le="0.0005"} 0
+dcshmd_hud_draw_duration_seconds_bucket{le="0.001"} 0
+dcshmd_hud_draw_duration_seconds_bucket{le="0.002"} 0
+dcshmd_hud_draw_duration_seconds_bucket{le="0.004"} 1
+dcshmd_hud_draw_duration_seconds_bucket{le="0.008"} 1
+dcshmd_hud_draw_duration_seconds_bucket{le="0.016"} 1
+dcshmd_hud_draw_duration_seconds_bucket{le="0.033"} 1
+dcshmd_hud_draw_duration_seconds_bucket{le="0.066"} 1
+dcshmd_hud_draw_duration_seconds_bucket{le="0.1"} 1
+dcshmd_hud_draw_duration_seconds_bucket{le="0.25"} 1
+dcshmd_hud_draw_duration_seconds_bucket{le="+Inf"} 2
+dcshmd_hud_draw_duration_seconds_sum 1.003
+dcshmd_hud_draw_duration_seconds_count 2
+# HELP dcshmd_hud_redraws_total Number of times the indicator image was drawn on the screen.
+# TYPE dcshmd_hud_redraws_total counter
+dcshmd_hud_redraws_total{indicator="rotor_pitch"} 2
+`, string(body))
+}
+
+func TestWriter_Escaping(t *testing.T) {
+	s, err := metrics.New("127.0.0.1:0", metrics.CollectorFunc(func(w *metrics.Writer) {
+		w.Family("test_metric", metrics.TypeGauge, "Help with \\ and\nnew line.")
+		w.Sample("test_metric", 1, "label", "quote \" backslash \\ new line \n")
+	}))
+	require.NoError(t, err)
+
+	defer s.Close()
+
+	resp, err := http.Get("http://" + s.Addr().String() + metrics.Path) //nolint:noctx // test request to the local server
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	require.Equal(t, `# HELP test_metric Help with \\ and\nnew line.
+# TYPE test_metric gauge
+test_metric{label="quote \" backslash \\ new line \n"} 1
+`, string(body))
+}
+
+type listenerStatsFunc func() updlistener.Stats
+
+func (f listenerStatsFunc) Stats() updlistener.Stats { return f() }
+
+type emptyValuesSetter struct{}
+
+func (s emptyValuesSetter) SetRotorPitch(float64)       {}
+func (s emptyValuesSetter) SetRotorRPM(float64)         {}
+func (s emptyValuesSetter) SetVerticalVelocity(float64) {}
@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Metric types of the text exposition format.
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+// Writer writes the metrics in the Prometheus text exposition format.
+type Writer struct {
+	w   *bufio.Writer
+	err error
+}
+
+func newWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// Family writes the help and the type of the metric family, it must precede the samples of the family.
+func (w *Writer) Family(name, typ, help string) {
+	w.writeString("# HELP " + name + " " + escapeHelp(help) + "\n")
+	w.writeString("# TYPE " + name + " " + typ + "\n")
+}
+
+// Sample writes the sample of the metric, labels are the pairs of the label names and values.
+func (w *Writer) Sample(name string, value float64, labels ...string) {
+	w.writeString(name)
+
+	if len(labels) > 0 {
+		w.writeString("{")
+
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				w.writeString(",")
+			}
+
+			w.writeString(labels[i] + `="` + escapeLabelValue(labels[i+1]) + `"`)
+		}
+
+		w.writeString("}")
+	}
+
+	w.writeString(" " + formatFloat(value) + "\n")
+}
+
+func (w *Writer) writeString(s string) {
+	if w.err == nil {
+		_, w.err = w.w.WriteString(s)
+	}
+}
+
+// flush writes the buffered data, it returns the first error that occurred.
+func (w *Writer) flush() error {
+	if w.err != nil {
+		return w.err
+	}
+
+	return w.w.Flush()
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+var (
+	helpReplacer  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpReplacer.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelReplacer.Replace(s)
+}
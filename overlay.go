@@ -0,0 +1,87 @@
+package dcshmd
+
+import (
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/dimchansky/dcs-hmd/diagnostics"
+)
+
+const (
+	// overlayUpdateInterval limits how often the overlay text is redrawn.
+	overlayUpdateInterval = time.Second / 4
+)
+
+// newOverlayHotkey returns the global hotkey that shows or hides the diagnostics overlay, so that it works while
+// DCS has the focus. The modifiers keep it apart from the F10 map view of DCS.
+func newOverlayHotkey() hotkey {
+	return hotkey{keys: []int{vkRControl, vkRShift, vkF10}}
+}
+
+// overlay is the diagnostics text drawn between the tapes.
+type overlay struct {
+	shown bool
+
+	img        *ebiten.Image
+	text       string
+	nextUpdate time.Time
+	NeedToDraw bool
+}
+
+// Update redraws the overlay image if the diagnostics have changed.
+func (o *overlay) Update(now time.Time, d *diagnostics.Diagnostics, layout *hudLayout, ff *FontFace) {
+	if !o.shown || d == nil || now.Before(o.nextUpdate) {
+		return
+	}
+
+	o.nextUpdate = now.Add(overlayUpdateInterval)
+
+	if o.img == nil {
+		size := layout.overlaySize()
+		o.img = ebiten.NewImage(size.X, size.Y)
+	}
+
+	// the lines that don't fit are cut off, one more line height is left for the descenders and the shadow
+	lines := d.Lines(now)
+	if maxLines := max(0, o.img.Bounds().Dy()/ff.lineHeight-1); len(lines) > maxLines {
+		lines = lines[:maxLines]
+	}
+
+	text := strings.Join(lines, "\n")
+	if text == o.text {
+		return
+	}
+
+	o.img.Clear()
+	ff.DrawTextWithShadow(o.img, text, 0, 0, textColor)
+	o.text = text
+	o.NeedToDraw = true
+}
+
+// DrawOn draws the overlay image if it has changed, the image is copied, so it clears the previous text.
+func (o *overlay) DrawOn(screen *ebiten.Image, layout *hudLayout) {
+	if !o.NeedToDraw {
+		return
+	}
+
+	op := &ebiten.DrawImageOptions{CompositeMode: ebiten.CompositeModeCopy}
+	pos := layout.overlayPos()
+	op.GeoM.Translate(float64(pos.X), float64(pos.Y))
+	screen.DrawImage(o.img, op)
+
+	o.NeedToDraw = false
+}
+
+// Reset disposes the overlay image, so that it is built again for the new layout.
+func (o *overlay) Reset() {
+	if o.img != nil {
+		o.img.Dispose()
+		o.img = nil
+	}
+
+	o.text = ""
+	o.nextUpdate = time.Time{}
+	o.NeedToDraw = false
+}
@@ -1,14 +1,18 @@
 package dcshmd
 
 import (
-	"bufio"
-	"bytes"
 	"embed"
+	"errors"
 	"fmt"
 	"io/fs"
+	"log/slog"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/dimchansky/dcs-hmd/installer"
 )
 
 //go:embed scripts/*
@@ -18,219 +22,284 @@ const (
 	// exportLuaFileName is a name of lua file that should be modified
 	exportLuaFileName = "Export.lua"
 
-	// exportLuaLine is a line to be added to Exports.lua file
-	exportLuaLine = "local lfs=require('lfs');dofile(lfs.writedir()..'Scripts/DCSHMD/Export.lua')"
+	// scriptsDirName is a name of the embedded directory with the scripts
+	scriptsDirName = "DCSHMD"
+
+	// DefaultScriptsFolder is a name of the folder in the scripts directory the scripts are installed to by default
+	DefaultScriptsFolder = scriptsDirName
+
+	// configLuaFileName is a name of lua file with the exporter configuration generated by installer
+	configLuaFileName = "Config.lua"
+
+	// versionLuaFileName is a name of lua file with the version of installer that installed the scripts
+	versionLuaFileName = "Version.lua"
+
+	// defaultExportLuaLine is a line to be added to Exports.lua file to load the scripts from the default folder
+	defaultExportLuaLine = "local lfs=require('lfs');dofile(lfs.writedir()..'Scripts/DCSHMD/Export.lua')"
 )
 
-// scriptsFS returns a sub-filesystem of the embedded scripts directory.
+// exportLuaLine returns the line to be added to Exports.lua file to load the scripts from the folder.
+// The scripts find the other ones by the DCSHMD_Folder global, it is not set for the default folder,
+// so that the line of the existing installations is not changed. Export.lua resets the global after use,
+// so that it doesn't leak to the hook of the default folder loaded after the one of another folder.
+func exportLuaLine(folder string) string {
+	if folder == DefaultScriptsFolder {
+		return defaultExportLuaLine
+	}
+
+	return fmt.Sprintf("local lfs=require('lfs');DCSHMD_Folder='%s';dofile(lfs.writedir()..'Scripts/%s/Export.lua')", folder, folder)
+}
+
+// validateScriptsFolder checks that the folder name can be used in the scripts directory and in lua string literal.
+func validateScriptsFolder(folder string) error {
+	if folder == "" || folder == "." || folder == ".." {
+		return fmt.Errorf("invalid scripts folder: '%s'", folder)
+	}
+
+	for _, r := range folder {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune(".-_ ", r)) {
+			return fmt.Errorf("invalid character %q in scripts folder '%s'", r, folder)
+		}
+	}
+
+	if strings.EqualFold(folder, installer.BackupDirName) {
+		return fmt.Errorf("scripts folder '%s' is reserved for backups", folder)
+	}
+
+	return nil
+}
+
+// scriptsFS returns a sub-filesystem of the embedded directory with the scripts.
 func scriptsFS() (fs.FS, error) {
-	return fs.Sub(scripts, "scripts")
+	return fs.Sub(scripts, path.Join("scripts", scriptsDirName))
 }
 
-// InstallScripts installs the scripts in the specified target directory.
+// InstallScripts installs the scripts in the folder of the specified target directory and stamps them with the version.
+// Only the files that differ are written, every file is backed up before it is changed and written atomically,
+// if the installation fails, the previous state is restored.
 // If verbose is true, the function prints detailed log messages to stdout.
-func InstallScripts(scriptsInstallDir string, verbose bool) error {
-	// check if scriptsInstallDir exists
+func InstallScripts(scriptsInstallDir, folder string, cfg *ExportConfig, version string, verbose bool) error {
+	slog.Info("installing scripts", "dir", scriptsInstallDir, "folder", folder, "targets", cfg.Targets)
+
 	if _, err := os.Stat(scriptsInstallDir); os.IsNotExist(err) {
 		return fmt.Errorf("folder does not exist: '%s'", scriptsInstallDir)
 	}
 
-	// replace the scripts in the target directory
-	if err := replaceScripts(scriptsInstallDir, verbose); err != nil {
+	plan, err := PlanInstall(scriptsInstallDir, folder, cfg, version)
+	if err != nil {
 		return err
 	}
 
-	// update the Export.lua script in the target directory
-	return updateExportScript(scriptsInstallDir, verbose)
+	return applyPlan(scriptsInstallDir, plan, verbose)
 }
 
-func replaceScripts(scriptsInstallDir string, verbose bool) error {
+// PlanInstall returns the file operations that install the scripts in the folder of the specified target directory,
+// nothing is changed. The target directory may not exist yet, then the plan is the one for the empty directory.
+func PlanInstall(scriptsInstallDir, folder string, cfg *ExportConfig, version string) (*installer.Plan, error) {
+	if err := validateScriptsFolder(folder); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
 	scripts, err := scriptsFS()
 	if err != nil {
-		return fmt.Errorf("failed to read embedded scripts directory: %w", err)
+		return nil, fmt.Errorf("failed to read embedded scripts directory: %w", err)
 	}
-	return fs.WalkDir(scripts, ".", func(path string, d fs.DirEntry, err error) error {
-		if path == "." {
-			return nil
-		}
-		targetPath := filepath.Join(scriptsInstallDir, path)
-		if d.IsDir() {
-			if verbose {
-				fmt.Printf("removing directory '%s'...\n", targetPath)
-			}
-			// remove target directory if it exists to clean up outdated scripts
-			if err := os.RemoveAll(targetPath); err != nil {
-				return fmt.Errorf("failed to clean up directory '%s': %w", targetPath, err)
-			}
-			if verbose {
-				fmt.Printf("creating directory '%s'...\n", targetPath)
-			}
-			if err := os.MkdirAll(targetPath, 0755); err != nil {
-				return fmt.Errorf("failed to create directory '%s': %w", targetPath, err)
-			}
-		} else {
-			data, err := fs.ReadFile(scripts, path)
-			if err != nil {
-				return fmt.Errorf("failed to read embedded file '%s': %w", path, err)
-			}
-			if verbose {
-				fmt.Printf("writing file '%s'...\n", targetPath)
-			}
-			if err := os.WriteFile(targetPath, data, 0644); err != nil {
-				return fmt.Errorf("failed to copy embedded file '%s' to '%s': %w", path, targetPath, err)
-			}
-		}
-		return nil
-	})
-}
 
-func updateExportScript(scriptsInstallDir string, verbose bool) error {
-	exportFile := filepath.Join(scriptsInstallDir, exportLuaFileName)
-	if _, err := os.Stat(exportFile); os.IsNotExist(err) {
-		if verbose {
-			fmt.Printf("creating script file '%s'...\n", exportFile)
-		}
-		if err := os.WriteFile(exportFile, []byte(fmt.Sprintln(exportLuaLine)), 0644); err != nil {
-			return fmt.Errorf("failed to create '%s' file: %w", exportFile, err)
-		}
-	} else {
-		if verbose {
-			fmt.Printf("checking script file '%s'...\n", exportFile)
+	var plan installer.Plan
+
+	installed := os.DirFS(scriptsInstallDir)
+
+	// write the scripts that differ from the embedded ones
+	err = fs.WalkDir(scripts, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
 		}
-		data, err := os.ReadFile(exportFile)
+
+		data, err := fs.ReadFile(scripts, name)
 		if err != nil {
-			return fmt.Errorf("failed to read the contents of '%s' script: %w", exportFile, err)
+			return fmt.Errorf("failed to read embedded file '%s': %w", name, err)
 		}
-		if !bytes.Contains(data, []byte(exportLuaLine)) {
-			if verbose {
-				fmt.Printf("prepending line into script file '%s'...\n", exportFile)
-			}
-			data = append([]byte(fmt.Sprintln(exportLuaLine)), data...)
-			if err := os.WriteFile(exportFile, data, 0644); err != nil {
-				return fmt.Errorf("failed to prepend line in '%s' script: %w", exportFile, err)
+
+		return plan.WriteFile(installed, path.Join(folder, name), data)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// clean up the outdated scripts
+	files, err := compareScripts(scripts, installed, folder)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range files {
+		if f.Status == installer.FileUnknown {
+			if err := plan.Delete(installed, f.Path); err != nil {
+				return nil, err
 			}
 		}
 	}
 
-	return nil
+	// write the exporter configuration and stamp the version of the installed scripts,
+	// so that the reinstallation with other options changes the configuration only
+	if err := plan.WriteFile(installed, path.Join(folder, configLuaFileName), cfg.luaConfig()); err != nil {
+		return nil, err
+	}
+
+	if err := plan.WriteFile(installed, path.Join(folder, versionLuaFileName), versionLua(version)); err != nil {
+		return nil, err
+	}
+
+	// load the scripts from the Export.lua script
+	if err := plan.PrependLine(installed, exportLuaFileName, exportLuaLine(folder)); err != nil {
+		return nil, err
+	}
+
+	return &plan, nil
 }
 
-// UninstallScripts uninstalls the scripts from the specified target directory.
+// UninstallScripts uninstalls the scripts from the folder of the specified target directory. Every file is backed up
+// before it is changed or removed, so the uninstallation can be rolled back.
 // If verbose is true, the function prints detailed log messages to stdout.
-func UninstallScripts(scriptsInstallDir string, verbose bool) error {
-	// Check if the scriptsInstallDir exists.
+func UninstallScripts(scriptsInstallDir, folder string, verbose bool) error {
+	slog.Info("uninstalling scripts", "dir", scriptsInstallDir, "folder", folder)
+
+	plan, err := PlanUninstall(scriptsInstallDir, folder)
+	if err != nil {
+		return err
+	}
+
+	return applyPlan(scriptsInstallDir, plan, verbose)
+}
+
+// PlanUninstall returns the file operations that uninstall the scripts from the folder of the specified
+// target directory, nothing is changed.
+func PlanUninstall(scriptsInstallDir, folder string) (*installer.Plan, error) {
+	if err := validateScriptsFolder(folder); err != nil {
+		return nil, err
+	}
+
 	if _, err := os.Stat(scriptsInstallDir); os.IsNotExist(err) {
-		return fmt.Errorf("folder does not exist: '%s'", scriptsInstallDir)
+		return nil, fmt.Errorf("folder does not exist: '%s'", scriptsInstallDir)
 	}
 
-	// Update the Export.lua script in the target directory by calling the removeExportScriptLine function.
-	if err := removeExportScriptLine(scriptsInstallDir, verbose); err != nil {
-		return err
+	var plan installer.Plan
+
+	installed := os.DirFS(scriptsInstallDir)
+
+	// stop loading the scripts from the Export.lua script
+	if err := plan.RemoveLine(installed, exportLuaFileName, exportLuaLine(folder)); err != nil {
+		return nil, err
+	}
+
+	// remove the scripts
+	if err := plan.Delete(installed, folder); err != nil {
+		return nil, err
 	}
 
-	// Remove the scripts in the target directory by calling the removeScripts function.
-	return removeScripts(scriptsInstallDir, verbose)
+	return &plan, nil
 }
 
-// removeScripts removes the scripts from the specified target directory.
-// If verbose is true, the function prints detailed log messages to stdout.
-func removeScripts(scriptsInstallDir string, verbose bool) error {
-	// Read the embedded scripts directory using the scriptsFS function.
-	scripts, err := scriptsFS()
+// compareScripts compares the scripts installed in the folder with the embedded ones, the paths of the checks
+// are relative to the scripts directory.
+func compareScripts(scripts, installed fs.FS, folder string) ([]installer.FileCheck, error) {
+	installedScripts, err := fs.Sub(installed, folder)
 	if err != nil {
-		return fmt.Errorf("failed to read embedded scripts directory: %w", err)
+		return nil, fmt.Errorf("failed to read installed scripts: %w", err)
 	}
 
-	// Walk through the embedded scripts directory.
-	return fs.WalkDir(scripts, ".", func(path string, d fs.DirEntry, err error) error {
-		if path == "." {
-			return nil
-		}
-		targetPath := filepath.Join(scriptsInstallDir, path)
-		if d.IsDir() {
-			if verbose {
-				fmt.Printf("removing directory '%s'...\n", targetPath)
-			}
-			// Remove the target directory if it exists to clean up outdated scripts.
-			if err := os.RemoveAll(targetPath); err != nil {
-				return fmt.Errorf("failed to clean up directory '%s': %w", targetPath, err)
-			}
-			return fs.SkipDir
-		} else {
-			if verbose {
-				fmt.Printf("removing file '%s'...\n", targetPath)
-			}
-			// Remove the target file if it exists.
-			if err := os.Remove(targetPath); err != nil && !os.IsNotExist(err) {
-				return fmt.Errorf("failed to remove file '%s': %w", targetPath, err)
+	files, err := installer.CompareFiles(scripts, installedScripts, configLuaFileName, versionLuaFileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare installed scripts: %w", err)
+	}
+
+	for i := range files {
+		files[i].Path = path.Join(folder, files[i].Path)
+	}
+
+	return files, nil
+}
+
+// applyPlan performs the planned file operations in the target directory in a transaction.
+func applyPlan(scriptsInstallDir string, plan *installer.Plan, verbose bool) error {
+	if plan.Empty() {
+		logStep(verbose, "nothing to do in", scriptsInstallDir)
+		return nil
+	}
+
+	return inTransaction(scriptsInstallDir, verbose, func(tx *installer.Transaction) error {
+		for i := range plan.Ops {
+			op := &plan.Ops[i]
+			logStep(verbose, op.Kind.String(), filepath.Join(scriptsInstallDir, filepath.FromSlash(op.Path)))
+
+			if err := op.Apply(tx); err != nil {
+				return err
 			}
 		}
+
 		return nil
 	})
 }
 
-// removeExportScriptLine removes the exportLuaLine from the Export.lua script in the specified target directory.
-// If verbose is true, the function prints detailed log messages to stdout.
-func removeExportScriptLine(scriptsInstallDir string, verbose bool) error {
-	exportFile := filepath.Join(scriptsInstallDir, exportLuaFileName)
-	if _, err := os.Stat(exportFile); os.IsNotExist(err) {
-		return nil
-	}
-
-	if verbose {
-		fmt.Printf("checking script file '%s'...\n", exportFile)
+// inTransaction calls the function in the transaction in the target directory, the transaction is committed
+// if the function succeeds, otherwise it is aborted.
+func inTransaction(scriptsInstallDir string, verbose bool, f func(tx *installer.Transaction) error) error {
+	// check if scriptsInstallDir exists
+	if _, err := os.Stat(scriptsInstallDir); os.IsNotExist(err) {
+		return fmt.Errorf("folder does not exist: '%s'", scriptsInstallDir)
 	}
 
-	lines, err := readLines(exportFile)
+	tx, err := installer.Begin(scriptsInstallDir, time.Now())
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to start installation: %w", err)
 	}
 
-	// in-place filtering
-	n := 0
-	for _, line := range lines {
-		if !strings.Contains(line, exportLuaLine) {
-			lines[n] = line
-			n++
+	if err := f(tx); err != nil {
+		logStep(verbose, "restoring previous state from backup", tx.BackupDir())
+
+		if abortErr := tx.Abort(); abortErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to restore previous state from backup '%s': %w", tx.BackupDir(), abortErr))
 		}
-	}
-	if n == len(lines) {
-		// no lines have been deleted from the slice
-		return nil
-	}
-	lines = lines[:n]
 
-	var output bytes.Buffer
-	for _, line := range lines {
-		fmt.Fprintln(&output, line)
+		return err
 	}
 
-	if verbose {
-		fmt.Printf("updating script file '%s'...\n", exportFile)
-	}
-	if err := os.WriteFile(exportFile, output.Bytes(), 0644); err != nil {
-		return fmt.Errorf("failed to update '%s' file: %w", exportFile, err)
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to complete installation: %w", err)
 	}
 
 	return nil
 }
 
-// readLines reads all the lines from the given file and returns them as a slice of strings.
-func readLines(filename string) ([]string, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open '%s' file: %w", filename, err)
-	}
-	defer file.Close()
+// RollbackScripts restores the target directory to the state before the last installation, uninstallation
+// or fix, the next call restores the state before the previous one.
+// If verbose is true, the function prints detailed log messages to stdout.
+func RollbackScripts(scriptsInstallDir string, verbose bool) error {
+	slog.Info("rolling back scripts", "dir", scriptsInstallDir)
 
-	var lines []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+	if _, err := os.Stat(scriptsInstallDir); os.IsNotExist(err) {
+		return fmt.Errorf("folder does not exist: '%s'", scriptsInstallDir)
 	}
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("failed to scan the contents of '%s' script: %w", filename, err)
+
+	backupDir, err := installer.Rollback(scriptsInstallDir)
+	if err != nil {
+		return fmt.Errorf("failed to roll back scripts: %w", err)
 	}
 
-	return lines, nil
+	logStep(verbose, "restored backup", backupDir)
+
+	return nil
+}
+
+// logStep logs the installation step at debug level, if verbose is true, it also prints the step to stdout.
+func logStep(verbose bool, step, path string) {
+	slog.Debug(step, "path", path)
+
+	if verbose {
+		fmt.Printf("%s '%s'...\n", step, path)
+	}
 }
@@ -0,0 +1,88 @@
+-- Encoder of the compact binary frames, see outputparser.BinaryFrame for the layout.
+-- DCS runs Lua 5.1 that has neither bitwise operations nor string.pack, so everything is done with arithmetic.
+
+local DCSHMD_Binary = {}
+
+DCSHMD_Binary.Magic = 0xDC
+DCSHMD_Binary.Version = 1
+DCSHMD_Binary.HeaderSize = 11
+DCSHMD_Binary.ValueSize = 6
+DCSHMD_Binary.ChecksumSize = 2
+
+function DCSHMD_Binary.Uint16(v)
+    return string.char(v % 256, math.floor(v / 256) % 256)
+end
+
+function DCSHMD_Binary.Uint32(v)
+    return string.char(v % 256, math.floor(v / 256) % 256, math.floor(v / 65536) % 256, math.floor(v / 16777216) % 256)
+end
+
+-- IEEE 754 single precision number, little-endian
+function DCSHMD_Binary.Float32(x)
+    local sign = 0
+    if x < 0 or (x == 0 and 1 / x < 0) then
+        sign = 1
+        x = -x
+    end
+
+    local exponent, mantissa
+    if x ~= x then -- NaN
+        return string.char(0, 0, 192, 127)
+    elseif x == math.huge then
+        exponent, mantissa = 255, 0
+    elseif x == 0 then
+        exponent, mantissa = 0, 0
+    else
+        local m, e = math.frexp(x) -- x = m * 2^e, 0.5 <= m < 1
+        exponent = e + 126
+        if exponent <= 0 then -- subnormal number
+            mantissa = math.floor(m * 2 ^ (23 + exponent) + 0.5)
+            exponent = 0
+        elseif exponent >= 255 then -- too large, infinity
+            exponent, mantissa = 255, 0
+        else
+            mantissa = math.floor((m * 2 - 1) * 2 ^ 23 + 0.5)
+            if mantissa == 2 ^ 23 then -- rounded up to the next power of two
+                exponent, mantissa = exponent + 1, 0
+            end
+        end
+    end
+
+    return string.char(
+        mantissa % 256,
+        math.floor(mantissa / 256) % 256,
+        math.floor(mantissa / 65536) % 128 + (exponent % 2) * 128,
+        sign * 128 + math.floor(exponent / 2)
+    )
+end
+
+function DCSHMD_Binary.Fletcher16(data)
+    local sum1, sum2 = 0, 0
+    for i = 1, #data do
+        sum1 = (sum1 + data:byte(i)) % 255
+        sum2 = (sum2 + sum1) % 255
+    end
+
+    return sum2 * 256 + sum1
+end
+
+-- Frame encodes the values, every value is the { argument ID, number } pair
+function DCSHMD_Binary.Frame(simID, seq, values)
+    local parts = {
+        string.char(DCSHMD_Binary.Magic, DCSHMD_Binary.Version),
+        DCSHMD_Binary.Uint32(simID),
+        DCSHMD_Binary.Uint32(seq),
+        string.char(#values),
+    }
+
+    for _, v in ipairs(values) do
+        table.insert(parts, DCSHMD_Binary.Uint16(v[1]))
+        table.insert(parts, DCSHMD_Binary.Float32(v[2]))
+    end
+
+    local frame = table.concat(parts)
+
+    return frame .. DCSHMD_Binary.Uint16(DCSHMD_Binary.Fletcher16(frame))
+end
+
+return DCSHMD_Binary
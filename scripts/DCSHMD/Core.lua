@@ -1,15 +1,61 @@
-dofile(lfs.writedir()..[[Scripts\DCSHMD\Util.lua]])
-dofile(lfs.writedir()..[[Scripts\DCSHMD\Udp.lua]])
+-- Config.lua and Version.lua set the globals, they are reset first, so that the ones of the installation
+-- in another folder loaded before are not used
+DCSHMD_Config = nil
+DCSHMD_Version = nil
 
-DCSHMD = {}
+-- Config.lua is generated by the installer, defaults are used if it is missing
+local configFile = DCSHMD_Dir..[[Config.lua]]
+if lfs.attributes(configFile) ~= nil then
+    dofile(configFile)
+end
+
+-- Version.lua is stamped by the installer
+local versionFile = DCSHMD_Dir..[[Version.lua]]
+if lfs.attributes(versionFile) ~= nil then
+    dofile(versionFile)
+end
+
+-- The state is kept in the locals, so that the installations in several folders don't share it
+local config, version = DCSHMD_Config, DCSHMD_Version
+
+local DCSHMD_Util = dofile(DCSHMD_Dir..[[Util.lua]])
+local DCSHMD_Udp = dofile(DCSHMD_Dir..[[Udp.lua]])
+
+local DCSHMD = {}
 
 DCSHMD.DebugFile = nil
-DCSHMD.Interval = 0.01 -- frequency of export events (sec)
+
+-- Export rates of the argument importance tiers (exports per second),
+-- the high importance arguments are exported on every export event
+DCSHMD.Rates = { High = 100, Medium = 20, Low = 2 }
+
+if config ~= nil and config.Rates ~= nil then
+    DCSHMD.Rates = config.Rates
+end
+
+-- Aircraft the cockpit arguments are exported for, see DCSHMD.AircraftArguments for the supported ones
+DCSHMD.Aircraft = { "Ka-50" }
+
+if config ~= nil and config.Aircraft ~= nil then
+    DCSHMD.Aircraft = config.Aircraft
+end
+
+DCSHMD.Interval = 1 / DCSHMD.Rates.High -- frequency of export events (sec)
+
+-- Number of export events between the exports of the medium and low importance arguments
+DCSHMD.MediumTicks = math.max(1, math.floor(DCSHMD.Rates.High / DCSHMD.Rates.Medium + 0.5))
+DCSHMD.LowTicks = math.max(1, math.floor(DCSHMD.Rates.High / DCSHMD.Rates.Low + 0.5))
+
+-- Export event counter, unlike DCSHMD_Udp.TickCount it is not reset by the keyframes
+DCSHMD.EventCount = 0
+
+-- All values are sent about once a second
+DCSHMD_Udp.KeyframeInterval = math.max(1, math.floor(DCSHMD.Rates.High + 0.5))
 
 function DCSHMD.Start()
     DCSHMD.DebugFile = io.open(lfs.writedir()..[[Logs\DCSHMDDebug.log]], "wa")
 
-    log.write('DCSHMD EXPORT',log.INFO,'Mission Started')
+    log.write('DCSHMD EXPORT',log.INFO,'Mission Started, version '..tostring(version))
 
     if not DCSHMD_Udp.Start() then
         if DCSHMD.DebugFile then
@@ -28,14 +74,22 @@ function DCSHMD.BeforeNextFrame()
 end
 
 function DCSHMD.ActivityNextEvent()
-    DCSHMD_Udp.TickCount = DCSHMD_Udp.TickCount + 1
+    DCSHMD_Udp.Tick()
+
+    local eventCount = DCSHMD.EventCount
+    DCSHMD.EventCount = eventCount + 1
 
     local selfdata = LoGetSelfData()
 
     -- Check if we are on an aircraft
     if selfdata == nil then return end
 
-    if DCSHMD_Util.CompareString(selfdata.Name, "Ka-50") then
+    -- The flight data is exported for every airframe
+    DCSHMD.ProcessFlightData(selfdata)
+
+    local arguments = DCSHMD.EnabledArguments(selfdata.Name)
+
+    if arguments ~= nil then
         local lDevice = GetDevice(0)
 
         if type(lDevice) == "table" then
@@ -43,11 +97,30 @@ function DCSHMD.ActivityNextEvent()
             lDevice:update_arguments()
 
             -- Handle the simple-case data that can be simply read via device:get_argument_value
-            DCSHMD.ProcessArguments(lDevice, DCSHMD.Ka50HighImportanceArguments)
+            DCSHMD.ProcessArguments(lDevice, arguments.High)
+
+            if eventCount % DCSHMD.MediumTicks == 0 then
+                DCSHMD.ProcessArguments(lDevice, arguments.Medium)
+            end
+
+            if eventCount % DCSHMD.LowTicks == 0 then
+                DCSHMD.ProcessArguments(lDevice, arguments.Low)
+            end
+        end
+    end
+
+    DCSHMD_Udp.Flush()
+end
 
-            DCSHMD_Udp.Flush()
+-- Returns the arguments of the aircraft if its export is enabled, otherwise nil
+function DCSHMD.EnabledArguments(name)
+    for _, aircraft in ipairs(DCSHMD.Aircraft) do
+        if DCSHMD_Util.CompareString(name, aircraft) and DCSHMD.AircraftArguments[aircraft] ~= nil then
+            return DCSHMD.AircraftArguments[aircraft]
         end
     end
+
+    return nil
 end
 
 function DCSHMD.Stop()
@@ -75,6 +148,82 @@ function DCSHMD.ProcessArguments(device, arguments)
     --end
 end
 
+-- Handles the sim-level flight data read via LoGet* functions, the values are in physical units
+function DCSHMD.ProcessFlightData(selfdata)
+    local channels = DCSHMD.FlightDataChannels
+
+    DCSHMD.SendFlightValue(channels.Heading, "%.4f", selfdata.Heading)
+
+    local position = selfdata.Position
+    if position ~= nil then
+        DCSHMD.SendFlightValue(channels.PositionX, "%.2f", position.x)
+        DCSHMD.SendFlightValue(channels.PositionY, "%.2f", position.y)
+        DCSHMD.SendFlightValue(channels.PositionZ, "%.2f", position.z)
+    end
+
+    if LoGetIndicatedAirSpeed then
+        DCSHMD.SendFlightValue(channels.IndicatedAirspeed, "%.2f", LoGetIndicatedAirSpeed())
+    end
+
+    if LoGetAltitudeAboveGroundLevel then
+        DCSHMD.SendFlightValue(channels.AltitudeAGL, "%.2f", LoGetAltitudeAboveGroundLevel())
+    end
+
+    if LoGetVectorVelocity then
+        local velocity = LoGetVectorVelocity()
+        if velocity ~= nil then
+            DCSHMD.SendFlightValue(channels.VelocityX, "%.2f", velocity.x)
+            DCSHMD.SendFlightValue(channels.VelocityY, "%.2f", velocity.y)
+            DCSHMD.SendFlightValue(channels.VelocityZ, "%.2f", velocity.z)
+        end
+    end
+
+    if LoGetAccelerationUnits then
+        local acceleration = LoGetAccelerationUnits()
+        if acceleration ~= nil then
+            DCSHMD.SendFlightValue(channels.AccelerationX, "%.3f", acceleration.x)
+            DCSHMD.SendFlightValue(channels.AccelerationY, "%.3f", acceleration.y)
+            DCSHMD.SendFlightValue(channels.AccelerationZ, "%.3f", acceleration.z)
+        end
+    end
+
+    if LoGetAngleOfAttack then
+        DCSHMD.SendFlightValue(channels.AngleOfAttack, "%.4f", LoGetAngleOfAttack())
+    end
+
+    if LoGetSlipBallPosition then
+        DCSHMD.SendFlightValue(channels.SlipBall, "%.3f", LoGetSlipBallPosition())
+    end
+end
+
+-- Sends the flight data value, the value is nil if the simulation doesn't provide it
+function DCSHMD.SendFlightValue(id, format, value)
+    if value == nil then return end
+
+    DCSHMD_Udp.Send(id, string.format(format, value))
+end
+
+-- Channel IDs of the flight data exported on every export event, they are above the cockpit argument numbers,
+-- see outputparser.FlightData for the units
+DCSHMD.FlightDataChannels =
+{
+    IndicatedAirspeed = 10001, -- m/s
+    AltitudeAGL = 10002,       -- m
+    VelocityX = 10003,         -- m/s in the world frame: x points north, y up and z east
+    VelocityY = 10004,
+    VelocityZ = 10005,
+    AccelerationX = 10006,     -- G in the body frame: x points forward, y up and z right
+    AccelerationY = 10007,
+    AccelerationZ = 10008,
+    AngleOfAttack = 10009,     -- rad
+    SlipBall = 10010,          -- -1 (full left) to 1 (full right)
+    Heading = 10011,           -- rad, true heading
+    PositionX = 10012,         -- m in the world frame, the same axes as of the velocity
+    PositionY = 10013,
+    PositionZ = 10014
+}
+
+-- Arguments exported on every export event at DCSHMD.Rates.High
 DCSHMD.Ka50HighImportanceArguments =
 {
     -- VVI
@@ -86,4 +235,27 @@ DCSHMD.Ka50HighImportanceArguments =
     -- Rotor RPM
     ---------------------------------------------------
     [52]  = "%.4f"   		-- RotorRPM input={0.0, 110.0} output={0.0,1.0}
-}
\ No newline at end of file
+}
+
+-- Arguments exported at DCSHMD.Rates.Medium
+DCSHMD.Ka50MediumImportanceArguments =
+{
+}
+
+-- Slow-changing arguments, e.g. fuel or engine temperatures, exported at DCSHMD.Rates.Low
+DCSHMD.Ka50LowImportanceArguments =
+{
+}
+
+-- Arguments of the supported aircraft by importance tier
+DCSHMD.AircraftArguments =
+{
+    ["Ka-50"] =
+    {
+        High = DCSHMD.Ka50HighImportanceArguments,
+        Medium = DCSHMD.Ka50MediumImportanceArguments,
+        Low = DCSHMD.Ka50LowImportanceArguments
+    }
+}
+
+return DCSHMD
@@ -1,7 +1,13 @@
 package.path = package.path..";.\\LuaSocket\\?.lua;"
 package.cpath = package.cpath..";.\\LuaSocket\\?.dll;"
 
-dofile(lfs.writedir()..[[Scripts\DCSHMD\Core.lua]])
+-- The folder the scripts are installed to, the hook in Export.lua sets DCSHMD_Folder if it is not the default one.
+-- It is reset, so that the hook of the default folder loaded after this one doesn't use it.
+DCSHMD_Dir = lfs.writedir()..[[Scripts\]]..(DCSHMD_Folder or "DCSHMD")..[[\]]
+DCSHMD_Folder = nil
+
+-- The exporter state is local, so that the installations in several folders don't share it
+local DCSHMD = dofile(DCSHMD_Dir..[[Core.lua]])
 
 local PrevExport = {}
 PrevExport.LuaExportStart = LuaExportStart
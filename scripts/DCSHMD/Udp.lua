@@ -1,23 +1,55 @@
 local socket = require("socket")
 
-DCSHMD_Udp = {}
+local DCSHMD_Binary = dofile(DCSHMD_Dir..[[Binary.lua]])
 
-DCSHMD_Udp.Host = "127.0.0.1"
-DCSHMD_Udp.Port = 19089
+local DCSHMD_Udp = {}
+
+-- Addresses to send data to: unicast, broadcast or multicast group addresses
+DCSHMD_Udp.Targets = {
+    { Host = "127.0.0.1", Port = 19089 },
+}
+
+-- Wire format: "text" or compact "binary" (UDP only)
+DCSHMD_Udp.Protocol = "text"
+
+if DCSHMD_Config ~= nil then
+    if DCSHMD_Config.Targets ~= nil then
+        DCSHMD_Udp.Targets = DCSHMD_Config.Targets
+    end
+
+    if DCSHMD_Config.Protocol ~= nil then
+        DCSHMD_Udp.Protocol = DCSHMD_Config.Protocol
+    end
+end
 
 DCSHMD_Udp.Socket = nil
 
 -- Simulation id
-DCSHMD_Udp.SimID = string.format("%08x*", os.time())
+DCSHMD_Udp.SimID = os.time()
+
+-- Sequence number of the packet, the receiver detects lost, duplicate and out-of-order packets by it
+DCSHMD_Udp.Seq = 0
+
+-- Maximal packet size and maximal number of values in the binary frame that fits in it
+DCSHMD_Udp.MaxPacketSize = 576
+DCSHMD_Udp.MaxBinaryValues = math.floor((DCSHMD_Udp.MaxPacketSize - DCSHMD_Binary.HeaderSize - DCSHMD_Binary.ChecksumSize) / DCSHMD_Binary.ValueSize)
 
 -- State data for export
 DCSHMD_Udp.PacketSize = 0
 DCSHMD_Udp.SendStrings = {}
+DCSHMD_Udp.SendValues = {}
 DCSHMD_Udp.LastData = {}
 
--- Frame counter for non important data
+-- Export event counter since the last keyframe
 DCSHMD_Udp.TickCount = 0
 
+-- All values are sent every KeyframeInterval ticks, so that the receiver started later or
+-- after the lost packets gets the values that have not changed
+DCSHMD_Udp.KeyframeInterval = 100
+
+-- The datagram the receiver sends back to request all values, see updlistener.RefreshRequest
+DCSHMD_Udp.RefreshRequest = "refresh"
+
 function DCSHMD_Udp.Start()
     if DCSHMD_Udp.Socket ~= nil then
         DCSHMD_Udp.Socket:close()
@@ -26,6 +58,7 @@ function DCSHMD_Udp.Start()
 
     DCSHMD_Udp.PacketSize = 0
     DCSHMD_Udp.SendStrings = {}
+    DCSHMD_Udp.SendValues = {}
     DCSHMD_Udp.LastData = {}
 
     DCSHMD_Udp.Socket = socket.udp()
@@ -33,8 +66,9 @@ function DCSHMD_Udp.Start()
     if DCSHMD_Udp.Socket == nil then return false end
 
     DCSHMD_Udp.Socket:setsockname("*", 0)
+    -- allows broadcast addresses (e.g. 192.168.1.255) in the targets
     DCSHMD_Udp.Socket:setoption('broadcast', true)
-    DCSHMD_Udp.Socket:settimeout(.001) -- set the timeout for reading the socket
+    DCSHMD_Udp.Socket:settimeout(0) -- reading the socket must not block the simulation
 
     return true
 end
@@ -51,38 +85,95 @@ function DCSHMD_Udp.Receive()
     return DCSHMD_Udp.Socket:receive()
 end
 
+-- Tick is called on every export event, it sends all values on the next flush when the keyframe is due
+-- or a receiver requests them
+function DCSHMD_Udp.Tick()
+    DCSHMD_Udp.TickCount = DCSHMD_Udp.TickCount + 1
+
+    local refresh = DCSHMD_Udp.TickCount >= DCSHMD_Udp.KeyframeInterval
+
+    local data = DCSHMD_Udp.Receive()
+    while data ~= nil do
+        if data == DCSHMD_Udp.RefreshRequest then
+            refresh = true
+        end
+
+        data = DCSHMD_Udp.Receive()
+    end
+
+    if refresh then
+        DCSHMD_Udp.ResetChangeValues()
+    end
+end
+
 function DCSHMD_Udp.Send(id, value)
     if string.len(value) > 3 and value == string.sub("-0.00000000", 1, string.len(value)) then
         value = value:sub(2)
     end
 
     if DCSHMD_Udp.LastData[id] == nil or DCSHMD_Udp.LastData[id] ~= value then
-        local data =  id .. "=" .. value
-        local dataLen = string.len(data)
+        if DCSHMD_Udp.Protocol == "binary" then
+            local number = tonumber(value)
 
-        if dataLen + DCSHMD_Udp.PacketSize > 576 then
-            DCSHMD_Udp.Flush()
-        end
+            -- the binary frame carries numbers only
+            if number == nil then return end
+
+            if #DCSHMD_Udp.SendValues >= DCSHMD_Udp.MaxBinaryValues then
+                DCSHMD_Udp.Flush()
+            end
+
+            table.insert(DCSHMD_Udp.SendValues, { id, number })
+        else
+            local data =  id .. "=" .. value
+            local dataLen = string.len(data)
 
-        table.insert(DCSHMD_Udp.SendStrings, data)
+            if dataLen + DCSHMD_Udp.PacketSize > DCSHMD_Udp.MaxPacketSize then
+                DCSHMD_Udp.Flush()
+            end
+
+            table.insert(DCSHMD_Udp.SendStrings, data)
+
+            DCSHMD_Udp.PacketSize = DCSHMD_Udp.PacketSize + dataLen + 1
+        end
 
         DCSHMD_Udp.LastData[id] = value
-        DCSHMD_Udp.PacketSize = DCSHMD_Udp.PacketSize + dataLen + 1
     end
 end
 
 function DCSHMD_Udp.Flush()
-    if #DCSHMD_Udp.SendStrings > 0 then
-        local packet = DCSHMD_Udp.SimID..table.concat(DCSHMD_Udp.SendStrings, ":").."\n"
+    local packet = nil
+
+    if #DCSHMD_Udp.SendValues > 0 then
+        packet = DCSHMD_Binary.Frame(DCSHMD_Udp.SimID, DCSHMD_Udp.Seq, DCSHMD_Udp.SendValues)
+    elseif #DCSHMD_Udp.SendStrings > 0 then
+        packet = string.format("%08x-%x*", DCSHMD_Udp.SimID, DCSHMD_Udp.Seq)..table.concat(DCSHMD_Udp.SendStrings, ":").."\n"
+    end
+
+    if packet == nil then return end
+
+    DCSHMD_Udp.Seq = (DCSHMD_Udp.Seq + 1) % 4294967296
 
-        socket.try(DCSHMD_Udp.Socket:sendto(packet, DCSHMD_Udp.Host, DCSHMD_Udp.Port))
+    DCSHMD_Udp.SendStrings = {}
+    DCSHMD_Udp.SendValues = {}
+    DCSHMD_Udp.PacketSize = 0
 
-        DCSHMD_Udp.SendStrings = {}
-        DCSHMD_Udp.PacketSize = 0
+    -- send to all targets even if some of them fail, then report the last error
+    local lastErr = nil
+    for _, target in ipairs(DCSHMD_Udp.Targets) do
+        local ok, err = DCSHMD_Udp.Socket:sendto(packet, target.Host, target.Port)
+        if not ok then
+            lastErr = err
+        end
+    end
+
+    if lastErr ~= nil then
+        error(lastErr)
     end
 end
 
 function DCSHMD_Udp.ResetChangeValues()
     DCSHMD_Udp.LastData = {}
-    DCSHMD_Udp.TickCount = 10
+    DCSHMD_Udp.TickCount = 0
 end
+
+return DCSHMD_Udp
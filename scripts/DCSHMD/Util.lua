@@ -1,4 +1,4 @@
-DCSHMD_Util = {}
+local DCSHMD_Util = {}
 
 function DCSHMD_Util.CompareString(str1, str2)
     if str1 == str2 or string.find(str1, str2, 1, true) ~= nil then
@@ -7,3 +7,5 @@ function DCSHMD_Util.CompareString(str1, str2)
 
     return false
 end
+
+return DCSHMD_Util
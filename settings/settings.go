@@ -0,0 +1,120 @@
+// Package settings persists user settings between program runs.
+package settings
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const (
+	appDirName       = "dcs-hmd"
+	settingsFileName = "settings.json"
+)
+
+// MinWindowWidth and MinWindowHeight are the minimal window size the HUD can be drawn in.
+const (
+	MinWindowWidth  = 200
+	MinWindowHeight = 150
+)
+
+// Settings holds the user settings that are remembered between program runs.
+type Settings struct {
+	Window Window `json:"window"`
+}
+
+// Window holds the HUD window placement.
+type Window struct {
+	// Monitor is an index of the monitor to show the HUD on, negative value means the current monitor.
+	Monitor int `json:"monitor"`
+	// X and Y are the window position relative to the upper-left corner of the monitor,
+	// negative values mean that the window position is chosen automatically.
+	X int `json:"x"`
+	Y int `json:"y"`
+	// Width and Height are the window size, zero values mean the default size.
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// Validate checks that the window is not smaller than the minimal size, the default size is always valid.
+func (w *Window) Validate() error {
+	if w.Width <= 0 || w.Height <= 0 {
+		return nil
+	}
+
+	if w.Width < MinWindowWidth || w.Height < MinWindowHeight {
+		return fmt.Errorf("window size %dx%d is less than the minimal size %dx%d",
+			w.Width, w.Height, MinWindowWidth, MinWindowHeight)
+	}
+
+	return nil
+}
+
+// Default returns the settings used when nothing has been saved yet.
+func Default() Settings {
+	return Settings{
+		Window: Window{
+			Monitor: -1,
+			X:       -1,
+			Y:       -1,
+		},
+	}
+}
+
+// DefaultPath returns the path to the settings file in the user configuration directory.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user configuration directory: %w", err)
+	}
+
+	return filepath.Join(dir, appDirName, settingsFileName), nil
+}
+
+// Load reads the settings from the file. If the file does not exist, the default settings are returned.
+// If the saved window size is invalid, the default size is returned with the error.
+func Load(path string) (Settings, error) {
+	s := Default()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return s, nil
+		}
+
+		return s, fmt.Errorf("failed to read settings file '%s': %w", path, err)
+	}
+
+	if err := json.Unmarshal(data, &s); err != nil {
+		return Default(), fmt.Errorf("failed to parse settings file '%s': %w", path, err)
+	}
+
+	// the saved size must not prevent the HUD from starting, so the invalid one is replaced with the default
+	if err := s.Window.Validate(); err != nil {
+		s.Window.Width, s.Window.Height = 0, 0
+		return s, fmt.Errorf("invalid settings file '%s': %w", path, err)
+	}
+
+	return s, nil
+}
+
+// Save writes the settings to the file, creating its directory if needed.
+func Save(path string, s *Settings) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal settings: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create settings directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write settings file '%s': %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_NotExist(t *testing.T) {
+	s, err := Load(filepath.Join(t.TempDir(), "settings.json"))
+	require.NoError(t, err)
+	require.Equal(t, Default(), s)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0644))
+
+	s, err := Load(path)
+	require.Error(t, err)
+	require.Equal(t, Default(), s)
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dcs-hmd", "settings.json")
+
+	want := Settings{
+		Window: Window{
+			Monitor: 1,
+			X:       10,
+			Y:       20,
+			Width:   1920,
+			Height:  1080,
+		},
+	}
+	require.NoError(t, Save(path, &want))
+
+	got, err := Load(path)
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+}
+
+func TestLoad_PartialKeepsDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"window":{"width":1024,"height":768}}`), 0644))
+
+	got, err := Load(path)
+	require.NoError(t, err)
+
+	want := Default()
+	want.Window.Width = 1024
+	want.Window.Height = 768
+	require.Equal(t, want, got)
+}
+
+func TestLoad_TooSmallWindow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"window":{"monitor":1,"width":10,"height":768}}`), 0644))
+
+	got, err := Load(path)
+	require.Error(t, err)
+
+	// the other settings are kept
+	want := Default()
+	want.Window.Monitor = 1
+	require.Equal(t, want, got)
+}
+
+func TestWindow_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		width   int
+		height  int
+		wantErr bool
+	}{
+		{"default size", 0, 0, false},
+		{"default width", 0, 10, false},
+		{"minimal size", MinWindowWidth, MinWindowHeight, false},
+		{"full HD", 1920, 1080, false},
+		{"narrow", 10, 1080, true},
+		{"low", 1920, MinWindowHeight - 1, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			w := Window{Width: tt.width, Height: tt.height}
+			if tt.wantErr {
+				require.Error(t, w.Validate())
+			} else {
+				require.NoError(t, w.Validate())
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package dcshmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/dimchansky/dcs-hmd/installer"
+)
+
+// versionRe matches the version in Version.lua.
+var versionRe = regexp.MustCompile(`DCSHMD_Version\s*=\s*"([^"]*)"`)
+
+// ScriptsStatus is the installation status of the scripts directory.
+type ScriptsStatus struct {
+	// Installed reports whether the scripts directory exists.
+	Installed bool
+	// Version is the version of the installer that installed the scripts, it is empty if unknown.
+	Version string
+	// Files are the installed files compared to the embedded ones.
+	Files []installer.FileCheck
+	// HookPresent reports whether Export.lua has the line that loads the scripts and it is not commented out.
+	HookPresent bool
+}
+
+// UpToDate reports whether the scripts of the version are installed, all the files match and the hook is present.
+func (s *ScriptsStatus) UpToDate(version string) bool {
+	if !s.Installed || !s.HookPresent || s.Version != version {
+		return false
+	}
+
+	for _, f := range s.Files {
+		if f.Status != installer.FileMatches {
+			return false
+		}
+	}
+
+	return true
+}
+
+// CheckScripts reports the status of the scripts installed in the folder of the specified target directory.
+func CheckScripts(scriptsInstallDir, folder string) (*ScriptsStatus, error) {
+	if err := validateScriptsFolder(folder); err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(scriptsInstallDir); os.IsNotExist(err) {
+		return nil, fmt.Errorf("folder does not exist: '%s'", scriptsInstallDir)
+	}
+
+	scripts, err := scriptsFS()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded scripts directory: %w", err)
+	}
+
+	var status ScriptsStatus
+
+	installed := os.DirFS(scriptsInstallDir)
+
+	if info, err := fs.Stat(installed, folder); err == nil && info.IsDir() {
+		status.Installed = true
+	}
+
+	versionPath := path.Join(folder, versionLuaFileName)
+
+	data, err := fs.ReadFile(installed, versionPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read version '%s': %w", filepath.Join(scriptsInstallDir, versionPath), err)
+	}
+
+	if m := versionRe.FindSubmatch(data); m != nil {
+		status.Version = string(m[1])
+	}
+
+	status.Files, err = compareScripts(scripts, installed, folder)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = fs.ReadFile(installed, exportLuaFileName)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read '%s' script: %w", filepath.Join(scriptsInstallDir, exportLuaFileName), err)
+	}
+
+	// the commented out hook doesn't load the scripts, the doctor doesn't count it either
+	status.HookPresent = installer.ContainsCode(string(data), exportLuaLine(folder))
+
+	return &status, nil
+}
+
+// versionLua returns the contents of Version.lua file.
+func versionLua(version string) []byte {
+	return []byte(fmt.Sprintf("-- Generated by dcs-hmd installer.\nDCSHMD_Version = %s\n", strconv.Quote(version)))
+}
@@ -0,0 +1,9 @@
+//go:build !windows
+
+package updlistener
+
+// isMsgSizeErr reports whether the error means that the datagram was larger than the buffer.
+// Other platforms silently truncate such datagrams, the listener detects them by size.
+func isMsgSizeErr(error) bool {
+	return false
+}
@@ -0,0 +1,14 @@
+package updlistener
+
+import (
+	"errors"
+	"syscall"
+)
+
+// wsaEMsgSize is the WSAEMSGSIZE error code, Windows returns it when the datagram is larger than the buffer.
+const wsaEMsgSize = syscall.Errno(10040)
+
+// isMsgSizeErr reports whether the error means that the datagram was larger than the buffer.
+func isMsgSizeErr(err error) bool {
+	return errors.Is(err, wsaEMsgSize)
+}
@@ -0,0 +1,123 @@
+package updlistener
+
+import (
+	"log/slog"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/dimchansky/dcs-hmd/utils"
+)
+
+// Stats holds the listener counters.
+type Stats struct {
+	// Packets and Bytes count the handled datagrams (or lines of the stream) and their bytes.
+	Packets uint64
+	Bytes   uint64
+	// Truncated counts the datagrams (or lines) that are larger than the maximal packet size, they are dropped.
+	Truncated uint64
+	// Dropped counts the datagrams from not allowed peers and failed reads.
+	Dropped uint64
+	// LastSender is the address of the last handled datagram sender, it is nil if nothing has been received.
+	LastSender net.Addr
+	// LastPacketTime is the time the last datagram was handled, it is zero if nothing has been received.
+	LastPacketTime time.Time
+}
+
+const (
+	// dropLogInterval and dropLogBurst limit the rate of the warnings about dropped packets and broken streams.
+	dropLogInterval = time.Minute
+	dropLogBurst    = 10
+)
+
+// counters collect the listener statistics, they are updated by the listening goroutine and read by any other.
+type counters struct {
+	packets        atomic.Uint64
+	bytes          atomic.Uint64
+	truncated      atomic.Uint64
+	dropped        atomic.Uint64
+	lastSender     atomic.Value // sender
+	lastPacketTime atomic.Int64
+
+	dropLog *utils.RateLimiter
+}
+
+func newCounters() *counters {
+	return &counters{dropLog: utils.NewRateLimiter(dropLogInterval, dropLogBurst)}
+}
+
+// sender wraps the address, so that atomic.Value always stores the same concrete type.
+type sender struct {
+	addr net.Addr
+}
+
+// received counts the handled packet of n bytes.
+func (c *counters) received(n int, from net.Addr) {
+	c.packets.Add(1)
+	c.bytes.Add(uint64(n))
+	c.lastPacketTime.Store(time.Now().UnixNano())
+
+	if from != nil {
+		if last, _ := c.lastSender.Load().(sender); !sameAddr(last.addr, from) {
+			c.lastSender.Store(sender{addr: from})
+		}
+	}
+}
+
+// sameAddr reports whether the addresses are equal. The UDP addresses are compared by value, since every read
+// returns the new one.
+func sameAddr(a, b net.Addr) bool {
+	ua, ok := a.(*net.UDPAddr)
+	if !ok {
+		return a == b
+	}
+
+	ub, ok := b.(*net.UDPAddr)
+
+	return ok && ua.AddrPort() == ub.AddrPort()
+}
+
+// truncate counts the packet that is too large and logs it.
+func (c *counters) truncate(args ...any) {
+	c.truncated.Add(1)
+	c.warn("packet is too large, dropped", args...)
+}
+
+// drop counts the dropped packet and logs the reason.
+func (c *counters) drop(reason string, args ...any) {
+	c.dropped.Add(1)
+	c.warn(reason, args...)
+}
+
+// warn logs the warning about the dropped packet or broken stream, the messages are rate-limited.
+func (c *counters) warn(msg string, args ...any) {
+	allowed, suppressed := c.dropLog.Allow(time.Now())
+	if !allowed {
+		return
+	}
+
+	if suppressed > 0 {
+		args = append(args, "suppressed", suppressed)
+	}
+
+	slog.Warn(msg, args...)
+}
+
+func (c *counters) stats() Stats {
+	s := Stats{
+		Packets:   c.packets.Load(),
+		Bytes:     c.bytes.Load(),
+		Truncated: c.truncated.Load(),
+		Dropped:   c.dropped.Load(),
+	}
+
+	if last, ok := c.lastSender.Load().(sender); ok {
+		s.LastSender = last.addr
+	}
+
+	if t := c.lastPacketTime.Load(); t != 0 {
+		s.LastPacketTime = time.Unix(0, t)
+	}
+
+	return s
+}
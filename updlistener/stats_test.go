@@ -0,0 +1,24 @@
+package updlistener
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_counters_receivedKeepsSameSender(t *testing.T) {
+	c := newCounters()
+
+	first := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5555}
+	c.received(1, first)
+
+	// every read returns the new address of the same sender
+	c.received(1, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5555})
+	require.Same(t, first, c.stats().LastSender)
+
+	other := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5556}
+	c.received(1, other)
+	require.Same(t, other, c.stats().LastSender)
+	require.Equal(t, uint64(3), c.stats().Packets)
+}
@@ -0,0 +1,272 @@
+package updlistener
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// streamBufSize is the maximal length of the line read from the stream, longer lines are skipped.
+	streamBufSize = 64 * 1024
+
+	// defaultReconnectDelay is the delay before the next attempt to open a stream after a failure.
+	defaultReconnectDelay = time.Second
+)
+
+// Listener is a source of the messages, it feeds the message handler until it is closed.
+// UPDListener and StreamListener implement it.
+type Listener interface {
+	Close() error
+	// Stats returns the current values of the listener counters, it is thread-safe.
+	Stats() Stats
+}
+
+// openStreamFunc opens the next stream of lines, it blocks until the stream is available or ctx is done.
+type openStreamFunc func(ctx context.Context) (io.ReadCloser, error)
+
+// StreamListener feeds the message handler from a stream of lines, e.g. TCP connection or WebSocket.
+// The lines are handled exactly like the UDP packets: the lines that do not fit in the buffer are skipped.
+// When the stream breaks, the listener opens a new one, so it reconnects until it is closed.
+type StreamListener struct {
+	closeOnce sync.Once
+	closeErr  error
+	closeCh   chan struct{}
+
+	ctx            context.Context
+	cancel         context.CancelFunc
+	open           openStreamFunc
+	closeSource    func() error
+	reconnectDelay time.Duration
+	msgHandler     MessageHandler
+	localAddr      net.Addr
+
+	streamMutex sync.Mutex
+	stream      io.ReadCloser
+
+	counters *counters
+}
+
+func newStreamListener(open openStreamFunc, closeSource func() error, msgHandler MessageHandler) *StreamListener {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	l := &StreamListener{
+		closeCh:        make(chan struct{}),
+		ctx:            ctx,
+		cancel:         cancel,
+		open:           open,
+		closeSource:    closeSource,
+		reconnectDelay: defaultReconnectDelay,
+		msgHandler:     msgHandler,
+		counters:       newCounters(),
+	}
+
+	go l.listen()
+
+	return l
+}
+
+// DialTCP connects to the TCP address and reads lines from the connection, it reconnects when the connection breaks.
+func DialTCP(address string, msgHandler MessageHandler) *StreamListener {
+	var dialer net.Dialer
+
+	return newStreamListener(func(ctx context.Context) (io.ReadCloser, error) {
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to tcp address '%s': %w", address, err)
+		}
+
+		return conn, nil
+	}, nil, msgHandler)
+}
+
+// ListenTCP listens on the TCP address and reads lines from the accepted connections one by one,
+// it accepts the next connection when the current one is closed.
+func ListenTCP(address string, msgHandler MessageHandler) (*StreamListener, error) {
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen tcp address '%s': %w", address, err)
+	}
+
+	l := newStreamListener(func(context.Context) (io.ReadCloser, error) {
+		conn, err := ln.Accept()
+		if err != nil {
+			return nil, fmt.Errorf("failed to accept tcp connection: %w", err)
+		}
+
+		return conn, nil
+	}, ln.Close, msgHandler)
+	l.localAddr = ln.Addr()
+
+	slog.Info("listening for exported data", "transport", "tcp", "address", l.localAddr)
+
+	return l, nil
+}
+
+// DialWebSocket connects to the WebSocket URL and reads lines from the messages, every message is terminated
+// by a new line if it doesn't end with one. It reconnects when the connection breaks.
+func DialWebSocket(url string, msgHandler MessageHandler) *StreamListener {
+	return newStreamListener(func(ctx context.Context) (io.ReadCloser, error) {
+		conn, resp, err := websocket.DefaultDialer.DialContext(ctx, url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to websocket '%s': %w", url, err)
+		}
+
+		_ = resp.Body.Close()
+
+		return &webSocketStream{conn: conn}, nil
+	}, nil, msgHandler)
+}
+
+// Addr returns the local address of the listener started by ListenTCP, it returns nil for other listeners.
+func (l *StreamListener) Addr() net.Addr {
+	return l.localAddr
+}
+
+// Stats returns the current values of the listener counters, every line of the stream is counted as a packet.
+func (l *StreamListener) Stats() Stats {
+	return l.counters.stats()
+}
+
+func (l *StreamListener) Close() error {
+	l.closeOnce.Do(func() {
+		l.cancel()
+
+		if l.closeSource != nil {
+			l.closeErr = l.closeSource()
+		}
+
+		l.setStream(nil)
+
+		<-l.closeCh // wait until listener is stopped
+	})
+
+	return l.closeErr
+}
+
+func (l *StreamListener) listen() {
+	defer close(l.closeCh)
+
+	ctx := l.ctx
+
+	for ctx.Err() == nil {
+		stream, err := l.open(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				l.counters.warn("failed to open stream", "error", err)
+			}
+
+			// wait before the next attempt
+			select {
+			case <-ctx.Done():
+			case <-time.After(l.reconnectDelay):
+			}
+
+			continue
+		}
+
+		if !l.setStream(stream) {
+			return
+		}
+
+		var from net.Addr
+		if s, ok := stream.(interface{ RemoteAddr() net.Addr }); ok {
+			from = s.RemoteAddr()
+		}
+
+		slog.Info("stream is opened", "from", from)
+
+		readLines(bufio.NewReaderSize(stream, streamBufSize), l.msgHandler, l.counters, from)
+
+		l.setStream(nil)
+
+		slog.Info("stream is closed", "from", from)
+	}
+}
+
+// setStream closes the current stream and replaces it, it returns false and closes the stream
+// if the listener is closed.
+func (l *StreamListener) setStream(stream io.ReadCloser) bool {
+	m := &l.streamMutex
+	m.Lock()
+	defer m.Unlock()
+
+	if l.stream != nil {
+		_ = l.stream.Close()
+	}
+
+	l.stream = stream
+
+	if stream != nil && l.ctx.Err() != nil {
+		_ = stream.Close()
+		l.stream = nil
+
+		return false
+	}
+
+	return true
+}
+
+// webSocketStream reads WebSocket messages as one stream of lines.
+type webSocketStream struct {
+	conn     *websocket.Conn
+	message  io.Reader
+	lastByte byte
+}
+
+func (s *webSocketStream) Read(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	for {
+		if s.message == nil {
+			_, message, err := s.conn.NextReader()
+			if err != nil {
+				return 0, err
+			}
+
+			s.message = message
+		}
+
+		n, err := s.message.Read(b)
+		if n > 0 {
+			s.lastByte = b[n-1]
+			return n, nil
+		}
+
+		if errors.Is(err, io.EOF) {
+			s.message = nil
+
+			// terminate the message, so that it is not concatenated with the next one
+			if s.lastByte != '\n' {
+				s.lastByte = '\n'
+				b[0] = '\n'
+
+				return 1, nil
+			}
+
+			continue
+		}
+
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (s *webSocketStream) RemoteAddr() net.Addr {
+	return s.conn.RemoteAddr()
+}
+
+func (s *webSocketStream) Close() error {
+	return s.conn.Close()
+}
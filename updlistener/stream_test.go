@@ -0,0 +1,132 @@
+package updlistener
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+// chanMessageHandler sends the received messages to the channel.
+type chanMessageHandler chan string
+
+func (c chanMessageHandler) HandleMessage(msg []byte) { c <- string(msg) }
+
+func (c chanMessageHandler) requireMessages(t *testing.T, want ...string) {
+	t.Helper()
+
+	for _, w := range want {
+		select {
+		case got := <-c:
+			require.Equal(t, w, got)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("message '%s' was not received", w)
+		}
+	}
+}
+
+func TestDialTCP_Reconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer ln.Close()
+
+	received := make(chanMessageHandler, 10)
+
+	l := DialTCP(ln.Addr().String(), received)
+	defer l.Close()
+
+	// every connection sends some lines and breaks, the listener must reconnect
+	for _, lines := range []string{"123\n" + strings.Repeat("4", streamBufSize) + "\n456\n", "789\n"} {
+		conn, err := ln.Accept()
+		require.NoError(t, err)
+
+		_, err = conn.Write([]byte(lines))
+		require.NoError(t, err)
+		require.NoError(t, conn.Close())
+	}
+
+	received.requireMessages(t, "123", "456", "789")
+
+	stats := l.Stats()
+	require.Equal(t, uint64(3), stats.Packets)
+	require.Equal(t, uint64(9), stats.Bytes)
+	require.Equal(t, uint64(1), stats.Truncated)
+	require.Equal(t, ln.Addr().String(), stats.LastSender.String())
+}
+
+func TestListenTCP(t *testing.T) {
+	received := make(chanMessageHandler, 10)
+
+	l, err := ListenTCP("127.0.0.1:0", received)
+	require.NoError(t, err)
+
+	defer l.Close()
+
+	for _, lines := range []string{"123\r\n456", "789\n"} {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		require.NoError(t, err)
+
+		_, err = conn.Write([]byte(lines))
+		require.NoError(t, err)
+		require.NoError(t, conn.Close())
+	}
+
+	received.requireMessages(t, "123", "456", "789")
+}
+
+func TestDialWebSocket(t *testing.T) {
+	var upgrader websocket.Upgrader
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for _, msg := range []string{"123\n456", "789\n", "", "abc"} {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+				return
+			}
+		}
+
+		// wait until the client is gone
+		_, _, _ = conn.ReadMessage()
+	}))
+	defer srv.Close()
+
+	received := make(chanMessageHandler, 10)
+
+	l := DialWebSocket("ws"+strings.TrimPrefix(srv.URL, "http"), received)
+
+	received.requireMessages(t, "123", "456", "789", "abc")
+
+	require.NoError(t, l.Close())
+}
+
+func TestStreamListener_CloseWhileConnecting(t *testing.T) {
+	// nobody listens on the address, so the listener keeps reconnecting
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	address := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	l := DialTCP(address, devNullMessageHandler{})
+
+	done := make(chan error, 1)
+	go func() { done <- l.Close() }()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("listener was not closed")
+	}
+}
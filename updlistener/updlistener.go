@@ -2,50 +2,157 @@ package updlistener
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net"
-	"strconv"
+	"net/netip"
 	"sync"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultPort is the default UDP port the exporter sends data to.
+	DefaultPort = 19089
+
+	// DefaultMaxPacketSize is the default maximal size of the datagram, it fits any UDP datagram.
+	DefaultMaxPacketSize = 64 * 1024
+
+	// RefreshRequest is the datagram sent back to the exporter to request all the values,
+	// otherwise it sends only the values that have changed.
+	RefreshRequest = "refresh"
 )
 
 type MessageHandler interface {
 	HandleMessage(msg []byte)
 }
 
+// SourceMessageHandler is implemented by the message handlers that need the address the message was sent from.
+// UPDListener calls HandleMessageFrom instead of HandleMessage for such handlers.
+type SourceMessageHandler interface {
+	HandleMessageFrom(msg []byte, addr *net.UDPAddr)
+}
+
 type MessageHandlerFunc func(msg []byte)
 
 func (f MessageHandlerFunc) HandleMessage(msg []byte) {
 	f(msg)
 }
 
-func New(port int, msgHandler MessageHandler) (*UPDListener, error) {
-	address := ":" + strconv.Itoa(port)
+// Config holds the listener options.
+type Config struct {
+	// Address is the UDP address in "host:port" form. If the host is empty, the listener binds all interfaces,
+	// use "127.0.0.1:port" to accept packets only from the local machine.
+	Address string
+	// MulticastGroup is an IP address of the multicast group to join, the port of Address is used.
+	// The listener does not join any group if it is empty.
+	MulticastGroup string
+	// MulticastInterface is a name of the network interface to join the multicast group on,
+	// the system-assigned interface is used if it is empty.
+	MulticastInterface string
+	// AllowedPeers is a list of the IP addresses the packets are accepted from.
+	// The packets from any address are accepted if it is empty.
+	AllowedPeers []net.IP
+	// MaxPacketSize is the maximal size of the datagram, larger datagrams are counted as truncated and dropped.
+	// DefaultMaxPacketSize is used if it is zero.
+	MaxPacketSize int
+	// RequestRefresh enables sending RefreshRequest to every new sender, so that the indicators don't show
+	// the defaults until the values change when the listener is started after the mission.
+	RequestRefresh bool
+}
 
-	udpAddr, err := net.ResolveUDPAddr("udp", address)
+func New(cfg *Config, msgHandler MessageHandler) (*UPDListener, error) {
+	conn, err := listenUDP(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to resolve udp address '%s': %w", address, err)
+		return nil, err
 	}
 
-	conn, err := net.ListenUDP("udp", udpAddr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to listen udp packet: %w", err)
+	maxPacketSize := cfg.MaxPacketSize
+	if maxPacketSize <= 0 {
+		maxPacketSize = DefaultMaxPacketSize
 	}
 
 	closeCh := make(chan struct{})
-	l := &UPDListener{closeCh: closeCh, conn: conn, msgHandler: msgHandler}
+	l := &UPDListener{
+		closeCh:        closeCh,
+		conn:           conn,
+		allowedPeers:   cfg.AllowedPeers,
+		maxPacketSize:  maxPacketSize,
+		requestRefresh: cfg.RequestRefresh,
+		msgHandler:     msgHandler,
+		counters:       newCounters(),
+	}
+
+	slog.Info("listening for exported data", "transport", "udp", "address", conn.LocalAddr(),
+		"multicastGroup", cfg.MulticastGroup, "allowedPeers", cfg.AllowedPeers)
 
 	go l.listen()
 
 	return l, nil
 }
 
+func listenUDP(cfg *Config) (*net.UDPConn, error) {
+	address := cfg.Address
+
+	udpAddr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve udp address '%s': %w", address, err)
+	}
+
+	if cfg.MulticastGroup == "" {
+		conn, err := net.ListenUDP("udp", udpAddr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen udp address '%s': %w", address, err)
+		}
+
+		return conn, nil
+	}
+
+	groupIP := net.ParseIP(cfg.MulticastGroup)
+	if groupIP == nil || !groupIP.IsMulticast() {
+		return nil, fmt.Errorf("invalid multicast group address: '%s'", cfg.MulticastGroup)
+	}
+
+	var ifi *net.Interface
+	if cfg.MulticastInterface != "" {
+		if ifi, err = net.InterfaceByName(cfg.MulticastInterface); err != nil {
+			return nil, fmt.Errorf("failed to find network interface '%s': %w", cfg.MulticastInterface, err)
+		}
+	}
+
+	groupAddr := &net.UDPAddr{IP: groupIP, Port: udpAddr.Port}
+
+	conn, err := net.ListenMulticastUDP("udp", ifi, groupAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to join multicast group '%s': %w", groupAddr, err)
+	}
+
+	return conn, nil
+}
+
 type UPDListener struct {
 	closeOnce sync.Once
 	closeErr  error
 	closeCh   chan struct{}
 
-	conn       *net.UDPConn
-	msgHandler MessageHandler
+	conn           *net.UDPConn
+	allowedPeers   []net.IP
+	maxPacketSize  int
+	requestRefresh bool
+	msgHandler     MessageHandler
+
+	counters *counters
+}
+
+// Stats returns the current values of the listener counters, it is thread-safe.
+func (l *UPDListener) Stats() Stats {
+	return l.counters.stats()
+}
+
+// LocalAddr returns the local network address the listener is bound to.
+func (l *UPDListener) LocalAddr() net.Addr {
+	return l.conn.LocalAddr()
 }
 
 func (l *UPDListener) Close() error {
@@ -57,19 +164,123 @@ func (l *UPDListener) Close() error {
 	return l.closeErr
 }
 
+// packetBufPool holds the buffers for reading datagrams of DefaultMaxPacketSize.
+var packetBufPool = sync.Pool{
+	New: func() any {
+		// one extra byte detects the datagrams that are larger than the maximal size
+		buf := make([]byte, DefaultMaxPacketSize+1)
+		return &buf
+	},
+}
+
 func (l *UPDListener) listen() {
 	closeCh := l.closeCh
 	defer close(closeCh)
 
-	const bufSize = 64 * 1024
+	pBuf := packetBufPool.Get().(*[]byte) //nolint:errcheck // the pool contains only *[]byte
+	defer packetBufPool.Put(pBuf)
+
+	buf := *pBuf
+	if l.maxPacketSize != DefaultMaxPacketSize {
+		buf = make([]byte, l.maxPacketSize+1)
+	}
 
-	reader := bufio.NewReaderSize(l.conn, bufSize)
 	msgHandler := l.msgHandler
+	srcMsgHandler, _ := msgHandler.(SourceMessageHandler)
 
-	readLines(reader, msgHandler)
+	// the senders the refresh has been requested from
+	refreshed := make(map[netip.AddrPort]struct{})
+
+	for {
+		n, addr, err := l.conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+
+			// e.g. ICMP port unreachable reported on Windows, the listener must keep going
+			if isMsgSizeErr(err) {
+				l.counters.truncate("error", err)
+			} else {
+				l.counters.drop("failed to read datagram", "error", err)
+			}
+
+			continue
+		}
+
+		if n > l.maxPacketSize {
+			l.counters.truncate("from", addr, "maxPacketSize", l.maxPacketSize)
+			continue
+		}
+
+		if len(l.allowedPeers) > 0 && !isAllowedPeer(l.allowedPeers, addr.IP) {
+			l.counters.drop("packet from not allowed peer, dropped", "from", addr)
+			continue
+		}
+
+		l.counters.received(n, addr)
+
+		if l.requestRefresh {
+			l.requestRefreshOnce(refreshed, addr)
+		}
+
+		if srcMsgHandler != nil {
+			handleDatagram(buf[:n], func(msg []byte) { srcMsgHandler.HandleMessageFrom(msg, addr) })
+		} else {
+			handleDatagram(buf[:n], msgHandler.HandleMessage)
+		}
+	}
 }
 
-func readLines(reader *bufio.Reader, msgHandler MessageHandler) {
+// requestRefreshOnce sends RefreshRequest to the sender if it has not been sent to it yet.
+func (l *UPDListener) requestRefreshOnce(refreshed map[netip.AddrPort]struct{}, addr *net.UDPAddr) {
+	addrPort := addr.AddrPort()
+	if _, ok := refreshed[addrPort]; ok {
+		return
+	}
+
+	refreshed[addrPort] = struct{}{}
+
+	if _, err := l.conn.WriteToUDPAddrPort([]byte(RefreshRequest), addrPort); err != nil {
+		l.counters.warn("failed to request refresh", "to", addr, "error", err)
+		return
+	}
+
+	slog.Debug("refresh requested", "from", addr)
+}
+
+// handleDatagram passes every line of the datagram to the handler. A datagram is a complete message even
+// if it doesn't end with a new line, so it is never concatenated with the next one.
+// The text messages are ASCII, so the datagram that starts with a non-ASCII byte is a binary frame,
+// it is passed to the handler as a whole.
+func handleDatagram(datagram []byte, handle func(msg []byte)) {
+	if len(datagram) > 0 && datagram[0] >= utf8.RuneSelf {
+		handle(datagram)
+		return
+	}
+
+	for len(datagram) > 0 {
+		line := datagram
+
+		if pos := bytes.IndexByte(datagram, '\n'); pos >= 0 {
+			line = datagram[:pos]
+			datagram = datagram[pos+1:]
+		} else {
+			datagram = nil
+		}
+
+		if len(line) > 0 && line[len(line)-1] == '\r' {
+			line = line[:len(line)-1]
+		}
+
+		if len(line) > 0 {
+			handle(line)
+		}
+	}
+}
+
+// readLines passes the lines read from the reader to the handler and counts them as packets received from the sender.
+func readLines(reader *bufio.Reader, msgHandler MessageHandler, c *counters, from net.Addr) {
 	nextIsContinuation := false
 
 	var (
@@ -86,9 +297,24 @@ func readLines(reader *bufio.Reader, msgHandler MessageHandler) {
 		}
 
 		if isContinuation || nextIsContinuation { // skip lines that do not fit in the buffer
+			if !isContinuation {
+				c.truncate("from", from, "maxPacketSize", reader.Size())
+			}
+
 			continue
 		}
 
+		c.received(len(message), from)
 		msgHandler.HandleMessage(message)
 	}
 }
+
+func isAllowedPeer(allowedPeers []net.IP, ip net.IP) bool {
+	for _, peer := range allowedPeers {
+		if peer.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
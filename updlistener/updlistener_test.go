@@ -3,9 +3,12 @@ package updlistener
 import (
 	"bufio"
 	"io"
+	"net"
+	"os"
 	"strings"
 	"testing"
 	"testing/iotest"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -40,13 +43,198 @@ func Test_readLines(t *testing.T) {
 			)
 
 			var msgHandler messageCollector
-			readLines(reader, &msgHandler)
+			readLines(reader, &msgHandler, newCounters(), nil)
 
 			require.Equal(t, tt.wantOutput, msgHandler.AsSlice())
 		})
 	}
 }
 
+func Test_handleDatagram(t *testing.T) {
+	tests := []struct {
+		name       string
+		datagram   string
+		wantOutput []string
+	}{
+		{"empty", "", nil},
+		{"single line without new line", "abc", []string{"abc"}},
+		{"single line", "abc\n", []string{"abc"}},
+		{"several lines", "abc\ndef\nghi", []string{"abc", "def", "ghi"}},
+		{"crlf", "abc\r\ndef\r\n", []string{"abc", "def"}},
+		{"empty lines are skipped", "\n\nabc\n\r\n\ndef\n", []string{"abc", "def"}},
+		{"binary frame is not split", "\xdc\x01\nabc\ndef", []string{"\xdc\x01\nabc\ndef"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var msgHandler messageCollector
+			handleDatagram([]byte(tt.datagram), msgHandler.HandleMessage)
+
+			require.Equal(t, tt.wantOutput, msgHandler.AsSlice())
+		})
+	}
+}
+
+func TestNew_Loopback(t *testing.T) {
+	received := make(chan string, 1)
+
+	l, err := New(&Config{Address: "127.0.0.1:0"}, MessageHandlerFunc(func(msg []byte) { received <- string(msg) }))
+	require.NoError(t, err)
+
+	defer l.Close()
+
+	conn, err := net.Dial("udp", l.conn.LocalAddr().String())
+	require.NoError(t, err)
+
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("637beb27*52=0.7792\n"))
+	require.NoError(t, err)
+
+	select {
+	case msg := <-received:
+		require.Equal(t, "637beb27*52=0.7792", msg)
+	case <-time.After(5 * time.Second):
+		t.Fatal("message was not received")
+	}
+}
+
+func TestNew_InvalidAddress(t *testing.T) {
+	_, err := New(&Config{Address: "127.0.0.1:port"}, devNullMessageHandler{})
+	require.Error(t, err)
+
+	_, err = New(&Config{Address: "127.0.0.1:0", MulticastGroup: "127.0.0.1"}, devNullMessageHandler{})
+	require.Error(t, err)
+}
+
+func TestNew_AllowedPeers(t *testing.T) {
+	received := make(chan string, 2)
+
+	l, err := New(&Config{
+		Address:      "127.0.0.1:0",
+		AllowedPeers: []net.IP{net.IPv4(127, 0, 0, 1)},
+	}, MessageHandlerFunc(func(msg []byte) { received <- string(msg) }))
+	require.NoError(t, err)
+
+	defer l.Close()
+
+	send := func(from, msg string) {
+		conn, err := net.DialUDP("udp",
+			&net.UDPAddr{IP: net.ParseIP(from)},
+			l.conn.LocalAddr().(*net.UDPAddr), //nolint:errcheck // UDPConn always has *net.UDPAddr
+		)
+		require.NoError(t, err)
+
+		defer conn.Close()
+
+		_, err = conn.Write([]byte(msg))
+		require.NoError(t, err)
+	}
+
+	send("127.0.0.2", "blocked\n")
+	send("127.0.0.1", "allowed\n")
+
+	select {
+	case msg := <-received:
+		require.Equal(t, "allowed", msg)
+	case <-time.After(5 * time.Second):
+		t.Fatal("message was not received")
+	}
+
+	require.Equal(t, uint64(1), l.Stats().Dropped)
+}
+
+func TestNew_Datagrams(t *testing.T) {
+	received := make(chan string, 4)
+
+	l, err := New(&Config{Address: "127.0.0.1:0", MaxPacketSize: 16},
+		MessageHandlerFunc(func(msg []byte) { received <- string(msg) }))
+	require.NoError(t, err)
+
+	defer l.Close()
+
+	conn, err := net.Dial("udp", l.LocalAddr().String())
+	require.NoError(t, err)
+
+	defer conn.Close()
+
+	for _, datagram := range []string{"1*24=0.5", "this one is too long\n", "2*52=0.1\n3*53=90"} {
+		_, err = conn.Write([]byte(datagram))
+		require.NoError(t, err)
+	}
+
+	// the datagram without trailing new line is not concatenated with the next one
+	for _, want := range []string{"1*24=0.5", "2*52=0.1", "3*53=90"} {
+		select {
+		case msg := <-received:
+			require.Equal(t, want, msg)
+		case <-time.After(5 * time.Second):
+			t.Fatal("message was not received")
+		}
+	}
+
+	stats := l.Stats()
+	require.Equal(t, uint64(2), stats.Packets)
+	require.Equal(t, uint64(len("1*24=0.5")+len("2*52=0.1\n3*53=90")), stats.Bytes)
+	require.Equal(t, uint64(1), stats.Truncated)
+	require.Equal(t, conn.LocalAddr().String(), stats.LastSender.String())
+	require.False(t, stats.LastPacketTime.IsZero())
+}
+
+func TestNew_SourceMessageHandler(t *testing.T) {
+	msgHandler := sourceCollector(make(chan string, 1))
+
+	l, err := New(&Config{Address: "127.0.0.1:0"}, msgHandler)
+	require.NoError(t, err)
+
+	defer l.Close()
+
+	conn, err := net.Dial("udp", l.LocalAddr().String())
+	require.NoError(t, err)
+
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("hello\n"))
+	require.NoError(t, err)
+
+	select {
+	case msg := <-msgHandler:
+		require.Equal(t, "hello from "+conn.LocalAddr().String(), msg)
+	case <-time.After(5 * time.Second):
+		t.Fatal("message was not received")
+	}
+}
+
+func TestNew_RequestRefresh(t *testing.T) {
+	l, err := New(&Config{Address: "127.0.0.1:0", RequestRefresh: true}, devNullMessageHandler{})
+	require.NoError(t, err)
+
+	defer l.Close()
+
+	conn, err := net.Dial("udp", l.LocalAddr().String())
+	require.NoError(t, err)
+
+	defer conn.Close()
+
+	buf := make([]byte, 64)
+
+	for i := 0; i < 2; i++ {
+		_, err = conn.Write([]byte("hello\n"))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, RefreshRequest, string(buf[:n]))
+
+	// the refresh is requested only once from the sender
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+	_, err = conn.Read(buf)
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+}
+
 func Benchmark_readLines(b *testing.B) {
 	rowsReader := simpleRowsReader(b.N)
 	reader := bufio.NewReaderSize(&rowsReader, 16)
@@ -55,7 +243,7 @@ func Benchmark_readLines(b *testing.B) {
 	b.ReportAllocs()
 	b.ResetTimer()
 
-	readLines(reader, msgHandler)
+	readLines(reader, msgHandler, newCounters(), nil)
 }
 
 type messageCollector []string
@@ -63,6 +251,13 @@ type messageCollector []string
 func (m *messageCollector) HandleMessage(msg []byte) { *m = append(*m, string(msg)) }
 func (m *messageCollector) AsSlice() []string        { return *m }
 
+type sourceCollector chan string
+
+func (c sourceCollector) HandleMessage(msg []byte) { c <- string(msg) }
+func (c sourceCollector) HandleMessageFrom(msg []byte, addr *net.UDPAddr) {
+	c <- string(msg) + " from " + addr.String()
+}
+
 type devNullMessageHandler struct{}
 
 func (d devNullMessageHandler) HandleMessage([]byte) {}
@@ -0,0 +1,7 @@
+//go:build !windows
+
+package utils
+
+func IsKeyDown(vk int) bool {
+	return false
+}
@@ -0,0 +1,8 @@
+package utils
+
+import "github.com/dimchansky/dcs-hmd/internal/win"
+
+// IsKeyDown reports whether the key with the virtual-key code is down, whichever window has the focus.
+func IsKeyDown(vk int) bool {
+	return win.GetAsyncKeyState(vk) < 0
+}
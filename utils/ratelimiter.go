@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter allows at most Burst events per Interval and counts the suppressed ones.
+// It is used to keep the repeating log messages from flooding the log. It is thread-safe.
+type RateLimiter struct {
+	Interval time.Duration
+	Burst    int
+
+	mu         sync.Mutex
+	start      time.Time
+	count      int
+	suppressed uint64
+}
+
+// NewRateLimiter creates a RateLimiter that allows burst events per interval.
+func NewRateLimiter(interval time.Duration, burst int) *RateLimiter {
+	return &RateLimiter{Interval: interval, Burst: burst}
+}
+
+// Allow reports whether the event is allowed at the moment. If it is, Allow also returns the number of events
+// suppressed since the previous allowed one and resets it.
+func (l *RateLimiter) Allow(now time.Time) (allowed bool, suppressed uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.start.IsZero() || now.Sub(l.start) >= l.Interval {
+		l.start = now
+		l.count = 0
+	}
+
+	if l.count >= l.Burst {
+		l.suppressed++
+		return false, 0
+	}
+
+	l.count++
+	suppressed, l.suppressed = l.suppressed, 0
+
+	return true, suppressed
+}
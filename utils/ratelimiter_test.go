@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	start := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+	l := NewRateLimiter(time.Minute, 2)
+
+	tests := []struct {
+		name           string
+		at             time.Duration
+		wantAllowed    bool
+		wantSuppressed uint64
+	}{
+		{"first", 0, true, 0},
+		{"second", time.Second, true, 0},
+		{"third is suppressed", 2 * time.Second, false, 0},
+		{"fourth is suppressed", 59 * time.Second, false, 0},
+		{"next interval reports suppressed", time.Minute + 2*time.Second, true, 2},
+		{"next interval second", time.Minute + 3*time.Second, true, 0},
+	}
+
+	for _, tt := range tests {
+		allowed, suppressed := l.Allow(start.Add(tt.at))
+		if allowed != tt.wantAllowed || suppressed != tt.wantSuppressed {
+			t.Errorf("%s: Allow() = %v, %v, want %v, %v", tt.name, allowed, suppressed, tt.wantAllowed, tt.wantSuppressed)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package dcshmd
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// linkDegradedText is shown while the packets are being lost, so the indicators may show stale values.
+const linkDegradedText = "LINK DEGRADED"
+
+// linkWarning is the warning drawn above the indicators when the link is degraded.
+type linkWarning struct {
+	shown bool
+
+	img        *ebiten.Image
+	NeedToDraw bool
+}
+
+// Update shows or hides the warning, it reports whether the warning has been hidden, so the screen must be cleared.
+func (w *linkWarning) Update(degraded bool, ff *FontFace) (hidden bool) {
+	if degraded != w.shown {
+		w.shown = degraded
+		if !degraded {
+			return true
+		}
+
+		w.NeedToDraw = true
+	}
+
+	if w.shown && w.img == nil {
+		w.img = ebiten.NewImage(ff.TextWidth(linkDegradedText)+2, ff.lineHeight+ff.lineHeight/2)
+		ff.DrawTextWithShadow(w.img, linkDegradedText, 0, 0, textColor)
+		w.NeedToDraw = true
+	}
+
+	return false
+}
+
+// DrawOn draws the warning if it has to be drawn.
+func (w *linkWarning) DrawOn(screen *ebiten.Image, layout *hudLayout) {
+	if !w.NeedToDraw || !w.shown {
+		return
+	}
+
+	pos := layout.linkWarningPos(w.img.Bounds().Size())
+
+	op := &ebiten.DrawImageOptions{CompositeMode: ebiten.CompositeModeCopy}
+	op.GeoM.Translate(float64(pos.X), float64(pos.Y))
+	screen.DrawImage(w.img, op)
+
+	w.NeedToDraw = false
+}
+
+// Reset disposes the warning image, so that it is built again for the new layout.
+func (w *linkWarning) Reset() {
+	if w.img != nil {
+		w.img.Dispose()
+		w.img = nil
+	}
+
+	w.NeedToDraw = false
+}
@@ -0,0 +1,204 @@
+// Package webhmd provides an HTTP server that renders the HMD tapes in a browser and pushes live values over WebSocket.
+package webhmd
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+//go:embed index.html
+var indexHTML []byte
+
+const (
+	// pushInterval is the minimal interval between two messages sent to a WebSocket client.
+	pushInterval = time.Second / 60
+	writeTimeout = time.Second
+)
+
+// Values is a snapshot of the values displayed on the HMD.
+type Values struct {
+	RotorPitch       float64 `json:"rotorPitch"`
+	RotorRPM         float64 `json:"rotorRPM"`
+	VerticalVelocity float64 `json:"verticalVelocity"`
+}
+
+// New starts the HTTP server on the address.
+// The server implements outputparser.ValuesSetter interface, so it can be fed by the output parser.
+func New(address string) (*Server, error) {
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen http address '%s': %w", address, err)
+	}
+
+	// the base context is canceled on close to stop WebSocket handlers, they are not tracked by http.Server
+	baseCtx, cancel := context.WithCancel(context.Background())
+
+	s := &Server{
+		closeCh: make(chan struct{}),
+		cancel:  cancel,
+		ln:      ln,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleIndex)
+	mux.HandleFunc("/values", s.handleValues)
+	mux.HandleFunc("/ws", s.handleWebSocket)
+
+	s.httpServer = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return baseCtx },
+	}
+
+	go s.serve()
+
+	return s, nil
+}
+
+type Server struct {
+	closeOnce sync.Once
+	closeErr  error
+	closeCh   chan struct{}
+	cancel    context.CancelFunc
+
+	ln         net.Listener
+	httpServer *http.Server
+	upgrader   websocket.Upgrader
+
+	rwMutex sync.RWMutex
+	values  Values
+	version uint64
+}
+
+// Addr returns the network address the server is listening on.
+func (s *Server) Addr() net.Addr {
+	return s.ln.Addr()
+}
+
+func (s *Server) Close() error {
+	s.closeOnce.Do(func() {
+		s.cancel()
+		s.closeErr = s.httpServer.Shutdown(context.Background())
+		<-s.closeCh // wait until server is stopped
+	})
+
+	return s.closeErr
+}
+
+// SetRotorPitch is thread-safe to update rotor pitch.
+func (s *Server) SetRotorPitch(val float64) {
+	s.update(func(v *Values) { v.RotorPitch = val })
+}
+
+// SetRotorRPM is thread-safe to update rotor RPM.
+func (s *Server) SetRotorRPM(val float64) {
+	s.update(func(v *Values) { v.RotorRPM = val })
+}
+
+// SetVerticalVelocity is thread-safe to update vertical velocity.
+func (s *Server) SetVerticalVelocity(val float64) {
+	s.update(func(v *Values) { v.VerticalVelocity = val })
+}
+
+// Values returns the latest values.
+func (s *Server) Values() Values {
+	values, _ := s.snapshot()
+	return values
+}
+
+func (s *Server) update(f func(v *Values)) {
+	m := &s.rwMutex
+	m.Lock()
+	f(&s.values)
+	s.version++
+	m.Unlock()
+}
+
+func (s *Server) snapshot() (values Values, version uint64) {
+	m := &s.rwMutex
+	m.RLock()
+	values, version = s.values, s.version
+	m.RUnlock()
+
+	return
+}
+
+func (s *Server) serve() {
+	defer close(s.closeCh)
+
+	_ = s.httpServer.Serve(s.ln)
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(indexHTML)
+}
+
+func (s *Server) handleValues(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.Values())
+}
+
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // upgrader has already replied with an error
+	}
+	defer conn.Close()
+
+	// read messages to process control frames and to detect that the client has gone
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	go func() {
+		defer cancel()
+
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	_ = pushValues(ctx, conn, s.snapshot)
+}
+
+// pushValues sends the values to the connection every time they change, but not more often than pushInterval.
+func pushValues(ctx context.Context, conn *websocket.Conn, snapshot func() (Values, uint64)) error {
+	ticker := time.NewTicker(pushInterval)
+	defer ticker.Stop()
+
+	sent := false
+
+	var sentVersion uint64
+
+	for {
+		if values, version := snapshot(); !sent || version != sentVersion {
+			_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := conn.WriteJSON(values); err != nil {
+				return fmt.Errorf("failed to write values: %w", err)
+			}
+
+			sent, sentVersion = true, version
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
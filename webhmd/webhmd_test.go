@@ -0,0 +1,118 @@
+package webhmd_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dimchansky/dcs-hmd/webhmd"
+)
+
+func newServer(t *testing.T) *webhmd.Server {
+	t.Helper()
+
+	s, err := webhmd.New("127.0.0.1:0")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { require.NoError(t, s.Close()) })
+
+	return s
+}
+
+func httpGet(t *testing.T, url string) (*http.Response, []byte) {
+	t.Helper()
+
+	resp, err := http.Get(url) //nolint:noctx // test request to the local server
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp, body
+}
+
+func TestServer_Index(t *testing.T) {
+	s := newServer(t)
+
+	resp, body := httpGet(t, "http://"+s.Addr().String()+"/")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.True(t, strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html"))
+	require.Contains(t, string(body), "<canvas")
+
+	resp, _ = httpGet(t, "http://"+s.Addr().String()+"/unknown")
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestServer_Values(t *testing.T) {
+	s := newServer(t)
+
+	s.SetRotorPitch(14.1068)
+	s.SetRotorRPM(85.712)
+	s.SetVerticalVelocity(-3)
+
+	resp, body := httpGet(t, "http://"+s.Addr().String()+"/values")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var got webhmd.Values
+	require.NoError(t, json.Unmarshal(body, &got))
+	require.Equal(t, webhmd.Values{RotorPitch: 14.1068, RotorRPM: 85.712, VerticalVelocity: -3}, got)
+}
+
+func TestServer_WebSocket(t *testing.T) {
+	s := newServer(t)
+
+	conn, resp, err := websocket.DefaultDialer.Dial("ws://"+s.Addr().String()+"/ws", nil)
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+	defer conn.Close()
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	// the current values are sent right after the connection
+	var got webhmd.Values
+	require.NoError(t, conn.ReadJSON(&got))
+	require.Equal(t, webhmd.Values{}, got)
+
+	s.SetRotorRPM(85.712)
+
+	require.NoError(t, conn.ReadJSON(&got))
+	require.Equal(t, webhmd.Values{RotorRPM: 85.712}, got)
+}
+
+func TestServer_CloseWithConnectedClient(t *testing.T) {
+	s, err := webhmd.New("127.0.0.1:0")
+	require.NoError(t, err)
+
+	conn, resp, err := websocket.DefaultDialer.Dial("ws://"+s.Addr().String()+"/ws", nil)
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+	defer conn.Close()
+
+	require.NoError(t, s.Close())
+
+	// the server closes the connection, so reading eventually fails
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				t.Fatal("connection was not closed by the server")
+			}
+
+			return
+		}
+	}
+}
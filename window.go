@@ -0,0 +1,52 @@
+package dcshmd
+
+import (
+	"fmt"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/dimchansky/dcs-hmd/settings"
+)
+
+// Monitors returns the names of the monitors available to the system, the first one is the primary monitor.
+// The index of the name can be used as the monitor index in the window settings.
+func Monitors() []string {
+	monitors := ebiten.AppendMonitors(nil)
+
+	names := make([]string, 0, len(monitors))
+	for _, m := range monitors {
+		names = append(names, m.Name())
+	}
+
+	return names
+}
+
+// applyWindowSettings moves the HUD window to the monitor and sets its position and size.
+func applyWindowSettings(w *settings.Window) error {
+	if err := w.Validate(); err != nil {
+		return err
+	}
+
+	if w.Monitor >= 0 {
+		monitors := ebiten.AppendMonitors(nil)
+		if w.Monitor >= len(monitors) {
+			return fmt.Errorf("monitor %d not found, number of available monitors: %d", w.Monitor, len(monitors))
+		}
+
+		ebiten.SetMonitor(monitors[w.Monitor])
+	}
+
+	width, height := w.Width, w.Height
+	if width <= 0 || height <= 0 {
+		width, height = ScreenWidth, ScreenHeight
+	}
+
+	ebiten.SetWindowSize(width, height)
+
+	// the position is relative to the upper-left corner of the monitor selected above
+	if w.X >= 0 && w.Y >= 0 {
+		ebiten.SetWindowPosition(w.X, w.Y)
+	}
+
+	return nil
+}